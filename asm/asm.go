@@ -0,0 +1,300 @@
+// Package asm implements an assembler and disassembler for CHIP-8 programs,
+// using the mnemonics from Cowgod's technical reference.
+//
+// A line consists of an optional label, an instruction or directive, and an
+// optional comment:
+//
+//   loop:   ld v0, 0x10     ; comments start with a semicolon
+//           jp loop
+//   SPEED   equ 4
+//   sprite: db 0xF0, %10010000, $90
+//           dw 0x1234
+//
+// Numbers are decimal, hexadecimal (0x, $ or #) or binary (0b or %), and can
+// be combined with labels and constants using + and -.
+//
+// The SUPER-CHIP and XO-CHIP instructions are supported as well. The 4 byte
+// XO-CHIP load of a 16-bit address is written as "ld i, long addr".
+package asm
+
+import (
+    "bufio"
+    "bytes"
+    "chip8-emulator/chip8"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Error describes a problem on a line of the source
+type Error struct {
+    Line    int
+    Message string
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ErrorList is returned when the source contains errors. It contains an
+// Error for every line that could not be assembled.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+    msgs := make([]string, len(l))
+    for i, e := range l {
+        msgs[i] = e.Error()
+    }
+    return strings.Join(msgs, "\n")
+}
+
+// statement is a single parsed line of source
+type statement struct {
+    line     int
+    address  int
+    mnemonic string
+    operands []string
+}
+
+type assembler struct {
+    statements []*statement
+    symbols    map[string]int
+    errors     ErrorList
+}
+
+// Assemble translates source into a program that is loaded at
+// chip8.ProgramStart
+func Assemble(source []byte) ([]byte, error) {
+    a := &assembler{
+        symbols: map[string]int{},
+    }
+    a.parse(source)
+    program := a.generate()
+    if len(a.errors) > 0 {
+        sort.SliceStable(a.errors, func(i, j int) bool {
+            return a.errors[i].Line < a.errors[j].Line
+        })
+        return nil, a.errors
+    }
+    if len(program) > chip8.MaxProgramSize {
+        return nil, fmt.Errorf("%w: %d bytes, at most %d bytes fit in memory", chip8.ErrProgramTooLarge, len(program), chip8.MaxProgramSize)
+    }
+    return program, nil
+}
+
+func (a *assembler) errorf(line int, format string, args ...interface{}) {
+    a.errors = append(a.errors, &Error{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// parse splits the source into statements, assigns their addresses and
+// defines the labels and constants
+func (a *assembler) parse(source []byte) {
+    address := chip8.ProgramStart
+    scanner := bufio.NewScanner(bytes.NewReader(source))
+    for line := 1; scanner.Scan(); line++ {
+        text := scanner.Text()
+        if i := strings.IndexByte(text, ';'); i >= 0 {
+            text = text[:i]
+        }
+        text = strings.TrimSpace(text)
+        fields := strings.Fields(text)
+        if len(fields) == 0 {
+            continue
+        }
+
+        if strings.HasSuffix(fields[0], ":") {
+            a.define(line, strings.TrimSuffix(fields[0], ":"), address)
+            text = strings.TrimSpace(text[strings.IndexByte(text, ':') + 1:])
+            fields = fields[1:]
+            if len(fields) == 0 {
+                continue
+            }
+        }
+
+        if len(fields) >= 3 && strings.EqualFold(fields[1], "equ") {
+            value, err := a.eval(strings.Join(fields[2:], ""))
+            if err != nil {
+                a.errorf(line, "%v", err)
+                continue
+            }
+            a.define(line, fields[0], value)
+            continue
+        }
+
+        s := &statement{
+            line:     line,
+            address:  address,
+            mnemonic: strings.ToLower(fields[0]),
+        }
+        if rest := strings.TrimSpace(text[len(fields[0]):]); rest != "" {
+            for _, op := range strings.Split(rest, ",") {
+                s.operands = append(s.operands, strings.TrimSpace(op))
+            }
+        }
+        a.statements = append(a.statements, s)
+        address += size(s)
+    }
+}
+
+// size returns the number of bytes a statement assembles to
+func size(s *statement) int {
+    switch s.mnemonic {
+    case "db":
+        return len(s.operands)
+    case "dw":
+        return len(s.operands) * 2
+    }
+    if _, ok := longAddress(s); ok {
+        return 4
+    }
+    return 2
+}
+
+func (a *assembler) define(line int, name string, value int) {
+    if !isIdentifier(name) {
+        a.errorf(line, "invalid name: %q", name)
+        return
+    }
+    key := strings.ToLower(name)
+    if _, ok := a.symbols[key]; ok {
+        a.errorf(line, "%q is already defined", name)
+        return
+    }
+    if _, ok := parseRegister(key); ok || reserved[key] {
+        a.errorf(line, "%q is a reserved name", name)
+        return
+    }
+    a.symbols[key] = value
+}
+
+// generate encodes all statements
+func (a *assembler) generate() []byte {
+    program := []byte{}
+    for _, s := range a.statements {
+        switch s.mnemonic {
+        case "db":
+            for _, op := range s.operands {
+                v, err := a.evalRange(op, 0xFF)
+                if err != nil {
+                    a.errorf(s.line, "%v", err)
+                }
+                program = append(program, byte(v))
+            }
+        case "dw":
+            for _, op := range s.operands {
+                v, err := a.evalRange(op, 0xFFFF)
+                if err != nil {
+                    a.errorf(s.line, "%v", err)
+                }
+                program = append(program, byte(v >> 8), byte(v))
+            }
+        default:
+            if expr, ok := longAddress(s); ok {
+                addr, err := a.evalRange(expr, 0xFFFF)
+                if err != nil {
+                    a.errorf(s.line, "%v", err)
+                }
+                program = append(program, 0xF0, 0x00, byte(addr >> 8), byte(addr))
+                continue
+            }
+            opCode, err := a.encode(s)
+            if err != nil {
+                a.errorf(s.line, "%v", err)
+            }
+            program = append(program, byte(opCode >> 8), byte(opCode))
+        }
+    }
+    return program
+}
+
+// eval evaluates an expression of numbers and symbols joined by + and -
+func (a *assembler) eval(expr string) (int, error) {
+    expr = strings.ReplaceAll(expr, " ", "")
+    total := 0
+    sign := 1
+    start := 0
+    for i := 0; i <= len(expr); i++ {
+        if i < len(expr) && expr[i] != '+' && expr[i] != '-' {
+            continue
+        }
+        if i < len(expr) && i == start {
+            // a sign in front of a term
+            if expr[i] == '-' {
+                sign = -sign
+            }
+            start = i + 1
+            continue
+        }
+        v, err := a.term(expr[start:i])
+        if err != nil {
+            return 0, err
+        }
+        total += sign * v
+        sign = 1
+        if i < len(expr) && expr[i] == '-' {
+            sign = -1
+        }
+        start = i + 1
+    }
+    return total, nil
+}
+
+func (a *assembler) term(term string) (int, error) {
+    if term == "" {
+        return 0, fmt.Errorf("missing value")
+    }
+    if v, ok := a.symbols[strings.ToLower(term)]; ok {
+        return v, nil
+    }
+    if isIdentifier(term) {
+        return 0, fmt.Errorf("undefined: %q", term)
+    }
+    return parseNumber(term)
+}
+
+// evalRange evaluates an expression and checks it is between 0 and max
+func (a *assembler) evalRange(expr string, max int) (int, error) {
+    v, err := a.eval(expr)
+    if err != nil {
+        return 0, err
+    }
+    if v < 0 || v > max {
+        return 0, fmt.Errorf("value out of range: %s = %d", expr, v)
+    }
+    return v, nil
+}
+
+func parseNumber(s string) (int, error) {
+    base := 10
+    digits := strings.ToLower(s)
+    switch {
+    case strings.HasPrefix(digits, "0x"):
+        base, digits = 16, digits[2:]
+    case strings.HasPrefix(digits, "$"), strings.HasPrefix(digits, "#"):
+        base, digits = 16, digits[1:]
+    case strings.HasPrefix(digits, "0b"):
+        base, digits = 2, digits[2:]
+    case strings.HasPrefix(digits, "%"):
+        base, digits = 2, digits[1:]
+    }
+    v, err := strconv.ParseInt(digits, base, 32)
+    if err != nil {
+        return 0, fmt.Errorf("invalid number: %q", s)
+    }
+    return int(v), nil
+}
+
+func isIdentifier(s string) bool {
+    if s == "" {
+        return false
+    }
+    for i, r := range s {
+        letter := r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+        digit := r >= '0' && r <= '9'
+        if !letter && (i == 0 || !digit) {
+            return false
+        }
+    }
+    return true
+}
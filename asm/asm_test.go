@@ -0,0 +1,278 @@
+package asm
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "errors"
+    "strings"
+    "testing"
+)
+
+func assemble(t *testing.T, source string) []byte {
+    t.Helper()
+    program, err := Assemble([]byte(source))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return program
+}
+
+func Test_Assemble_instructions(t *testing.T) {
+    program := assemble(t, `
+        cls
+        ret
+        sys 0x123
+        jp 0x204
+        jp v0, 0x300
+        call 0x208
+        se v1, 0x12
+        se v1, v2
+        sne v1, 0x12
+        sne v1, v2
+        ld v1, 0x20
+        ld v1, v2
+        add v1, 1
+        add v1, v2
+        or v1, v2
+        and v1, v2
+        xor v1, v2
+        sub v1, v2
+        shr v1
+        shr v1, v2
+        subn v1, v2
+        shl v1
+        ld i, 0x400
+        rnd v3, 0x0F
+        drw v1, v2, 5
+        skp v4
+        sknp v4
+        ld v5, dt
+        ld v5, k
+        ld dt, v5
+        ld st, v5
+        add i, v5
+        ld f, v5
+        ld b, v5
+        ld [i], v5
+        ld v5, [i]
+    `)
+
+    expected := chip8.Build(
+        chip8.CLS(),
+        chip8.RET(),
+        0x0123,
+        chip8.JP(0x204),
+        chip8.JP_R(0x300),
+        chip8.CALL(0x208),
+        chip8.SE(1, 0x12),
+        chip8.SE_R(1, 2),
+        chip8.SNE(1, 0x12),
+        chip8.SNE_R(1, 2),
+        chip8.LD(1, 0x20),
+        chip8.LD_R(1, 2),
+        chip8.ADD(1, 1),
+        chip8.ADD_R(1, 2),
+        chip8.OR(1, 2),
+        chip8.AND(1, 2),
+        chip8.XOR(1, 2),
+        chip8.SUB(1, 2),
+        0x8116,
+        0x8126,
+        chip8.SUBN(1, 2),
+        0x811E,
+        chip8.LDI(0x400),
+        chip8.RND(3, 0x0F),
+        chip8.DRW(1, 2, 5),
+        chip8.SKP(4),
+        chip8.SKNP(4),
+        chip8.LD_VX_DT(5),
+        chip8.LD_VX_K(5),
+        chip8.LD_DT_VX(5),
+        chip8.LD_ST_VX(5),
+        chip8.ADD_I(5),
+        chip8.LDF(5),
+        chip8.LDB(5),
+        chip8.LD_I_VX(5),
+        chip8.LD_VX_I(5),
+    )
+    if !bytes.Equal(program, expected) {
+        t.Errorf("unexpected program:\n%X\n%X", program, expected)
+    }
+}
+
+func Test_Assemble_superchip(t *testing.T) {
+    program := assemble(t, `
+        scd 4
+        scr
+        scl
+        exit
+        low
+        high
+        drw v1, v2, 0
+        ld hf, v3
+        ld r, v7
+        ld v7, r
+    `)
+
+    expected := chip8.Build(
+        chip8.SCD(4),
+        chip8.SCR(),
+        chip8.SCL(),
+        chip8.EXIT(),
+        chip8.LOW(),
+        chip8.HIGH(),
+        chip8.DRW(1, 2, 0),
+        chip8.LD_HF_VX(3),
+        chip8.LD_R_VX(7),
+        chip8.LD_VX_R(7),
+    )
+    if !bytes.Equal(program, expected) {
+        t.Errorf("unexpected program:\n%X\n%X", program, expected)
+    }
+
+    // every instruction disassembles to the same source
+    source := Disassemble(program).String()
+    assembled, err := Assemble([]byte(source))
+    if err != nil || !bytes.Equal(assembled, program) {
+        t.Errorf("listing does not round-trip: %v\n%s", err, source)
+    }
+}
+
+func Test_Assemble_xochip(t *testing.T) {
+    program := assemble(t, `
+        se v0, 0
+        ld i, long data
+        scu 2
+        save v1, v3
+        load v3, v1
+        plane 3
+        audio
+        ld pitch, v4
+        ld i, long 0xE000 + 2
+    data:
+        db 0xFF
+    `)
+
+    expected := chip8.Build(
+        chip8.SE(0, 0),
+        0xF000, 0x0216,
+        chip8.SCU(2),
+        chip8.SAVE(1, 3),
+        chip8.LOAD(3, 1),
+        chip8.PLANE(3),
+        chip8.AUDIO(),
+        chip8.LD_PITCH_VX(4),
+        0xF000, 0xE002,
+    )
+    expected = append(expected, 0xFF)
+    if !bytes.Equal(program, expected) {
+        t.Errorf("unexpected program:\n%X\n%X", program, expected)
+    }
+
+    listing := Disassemble(program)
+    if listing.Lines[1].Text != "ld i, long L216" || len(listing.Lines[1].Bytes) != 4 {
+        t.Errorf("unexpected long load: %+v", listing.Lines[1])
+    }
+    assembled, err := Assemble([]byte(listing.String()))
+    if err != nil || !bytes.Equal(assembled, program) {
+        t.Errorf("listing does not round-trip: %v\n%s", err, listing)
+    }
+}
+
+func Test_Assemble_labels_and_constants(t *testing.T) {
+    program := assemble(t, `
+        SPEED equ 4                 ; a constant
+        OFFSET equ SPEED + 2
+    start:
+        ld v0, SPEED
+        ld I, sprite
+    loop:   add v0, OFFSET - 1      ; label and instruction on one line
+        JP loop
+        call end
+    sprite:
+        db 0xF0, $90, #90, %10010000, 0b11110000
+    end: dw 0x1234, start
+    `)
+
+    expected := []byte{
+        0x60, 0x04,
+        0xA2, 0x0A,
+        0x70, 0x05,
+        0x12, 0x04,
+        0x22, 0x0F,
+        0xF0, 0x90, 0x90, 0x90, 0xF0,
+        0x12, 0x34, 0x02, 0x00,
+    }
+    if !bytes.Equal(program, expected) {
+        t.Errorf("unexpected program:\n%X\n%X", program, expected)
+    }
+}
+
+func Test_Assemble_runs(t *testing.T) {
+    program := assemble(t, `
+        ld v0, 5
+        ld v1, 0
+    loop:
+        add v1, 3
+        add v0, -1 + 256
+        se v0, 0
+        jp loop
+    `)
+    c, err := chip8.NewCPU(program)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 2 + 5 * 4; i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if c.Register[1] != 15 {
+        t.Errorf("unexpected v1: %v", c.Register[1])
+    }
+}
+
+func Test_Assemble_errors(t *testing.T) {
+    _, err := Assemble([]byte(`
+        cls
+        foo v1
+        ld v1, 0x100
+        jp missing
+    dup:
+    dup:
+        drw v1, v2
+        ld v1, v2 ; fine
+    `))
+
+    var list ErrorList
+    if !errors.As(err, &list) {
+        t.Fatalf("expected an error list, got %v", err)
+    }
+    lines := []int{}
+    for _, e := range list {
+        lines = append(lines, e.Line)
+    }
+    expected := []int{3, 4, 5, 7, 8}
+    if len(lines) != len(expected) {
+        t.Fatalf("unexpected errors: %v", err)
+    }
+    for i := range lines {
+        if lines[i] != expected[i] {
+            t.Errorf("unexpected errors: %v", err)
+            break
+        }
+    }
+    if !strings.Contains(err.Error(), `line 3: unknown instruction: "foo"`) {
+        t.Errorf("unexpected message: %v", err)
+    }
+}
+
+func Test_Assemble_too_large(t *testing.T) {
+    source := strings.Repeat("cls\n", chip8.MaxProgramSize / 2 + 1)
+    _, err := Assemble([]byte(source))
+
+    if !errors.Is(err, chip8.ErrProgramTooLarge) {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
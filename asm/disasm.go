@@ -0,0 +1,214 @@
+package asm
+
+import (
+    "chip8-emulator/chip8"
+    "fmt"
+    "io"
+    "strings"
+)
+
+// bytesPerData is the maximum number of bytes in a single db line
+const bytesPerData = 8
+
+// Line is a single line of a disassembly listing
+type Line struct {
+    Address uint16
+    Bytes   []byte
+    // Label is the label defined at Address, if any
+    Label string
+    // Text is the instruction, or a db directive for data
+    Text string
+    // Code is true if the line is an instruction reached from the entry
+    // point, and false for data
+    Code bool
+}
+
+// Listing is the disassembly of a program
+type Listing struct {
+    Lines []Line
+}
+
+// Disassemble turns a program loaded at chip8.ProgramStart into a listing.
+// Control flow is followed from the entry point to separate instructions
+// from data, and every jump, call and I target inside the program gets a
+// label. The listing assembles back into the same program.
+func Disassemble(program []byte) *Listing {
+    d := &disassembler{
+        program: program,
+        code:    make([]bool, len(program)),
+        long:    map[int]bool{},
+        labels:  map[uint16]string{},
+    }
+    d.trace(chip8.ProgramStart)
+    return d.listing()
+}
+
+type disassembler struct {
+    program []byte
+    // code is true for every offset in the program where an instruction
+    // starts
+    code   []bool
+    // long is true for the offsets of the 4 byte XO-CHIP F000 nnnn
+    long   map[int]bool
+    labels map[uint16]string
+}
+
+// opCode returns the instruction at addr, and false if it is outside the
+// program
+func (d *disassembler) opCode(addr int) (uint16, bool) {
+    offset := addr - chip8.ProgramStart
+    if offset < 0 || offset + 1 >= len(d.program) {
+        return 0, false
+    }
+    return uint16(d.program[offset]) << 8 | uint16(d.program[offset + 1]), true
+}
+
+// label defines a label for a target address inside the program
+func (d *disassembler) label(addr uint16) {
+    offset := int(addr) - chip8.ProgramStart
+    if offset >= 0 && offset < len(d.program) {
+        d.labels[addr] = fmt.Sprintf("L%03X", addr)
+    }
+}
+
+// trace marks all instructions reachable from entry as code
+func (d *disassembler) trace(entry int) {
+    pending := []int{entry}
+    for len(pending) > 0 {
+        addr := pending[len(pending) - 1]
+        pending = pending[:len(pending) - 1]
+
+        for {
+            opCode, ok := d.opCode(addr)
+            if !ok || d.code[addr - chip8.ProgramStart] {
+                break
+            }
+            in := chip8.Decode(opCode)
+            if in.Op == chip8.OpInvalid {
+                break
+            }
+            next := addr + in.Size()
+            if in.Op == chip8.OpLD_I_LONG {
+                target, ok := d.opCode(addr + 2)
+                if !ok {
+                    break
+                }
+                d.long[addr - chip8.ProgramStart] = true
+                d.label(target)
+            }
+            d.code[addr - chip8.ProgramStart] = true
+
+            switch in.Op {
+            case chip8.OpRET, chip8.OpEXIT:
+                next = -1
+            case chip8.OpJP:
+                d.label(in.NNN)
+                pending = append(pending, int(in.NNN))
+                next = -1
+            case chip8.OpCALL:
+                d.label(in.NNN)
+                pending = append(pending, int(in.NNN))
+            case chip8.OpLDI:
+                d.label(in.NNN)
+            case chip8.OpJP_R: // the target is unknown
+                d.label(in.NNN)
+                next = -1
+            case chip8.OpSE, chip8.OpSNE, chip8.OpSE_R, chip8.OpSNE_R, chip8.OpSKP, chip8.OpSKNP:
+                pending = append(pending, addr + 4)
+                // XO-CHIP skips the whole F000 nnnn
+                if skipped, _ := d.opCode(addr + 2); skipped == 0xF000 {
+                    pending = append(pending, addr + 6)
+                }
+            }
+            if next < 0 {
+                break
+            }
+            addr = next
+        }
+    }
+}
+
+func (d *disassembler) listing() *Listing {
+    l := &Listing{}
+    format := func(a uint16) string {
+        if label, ok := d.labels[a]; ok {
+            return label
+        }
+        return fmt.Sprintf("0x%03X", a)
+    }
+
+    for offset := 0; offset < len(d.program); {
+        addr := uint16(offset + chip8.ProgramStart)
+        size := 2
+        if d.long[offset] {
+            size = 4
+        }
+        // an instruction is only emitted if no label points into it past
+        // the first byte, otherwise it is emitted as data
+        splitByLabel := false
+        for i := 1; i < size; i++ {
+            if _, ok := d.labels[addr + uint16(i)]; ok {
+                splitByLabel = true
+            }
+        }
+        if d.code[offset] && !splitByLabel {
+            opCode, _ := d.opCode(int(addr))
+            text, _ := chip8.Mnemonic(opCode, format)
+            if size == 4 {
+                target, _ := d.opCode(int(addr) + 2)
+                text += " " + format(target)
+            }
+            l.Lines = append(l.Lines, Line{
+                Address: addr,
+                Bytes:   d.program[offset:offset + size],
+                Label:   d.labels[addr],
+                Text:    text,
+                Code:    true,
+            })
+            offset += size
+            continue
+        }
+
+        end := offset + 1
+        for end < len(d.program) && end - offset < bytesPerData && !d.code[end] {
+            if _, ok := d.labels[uint16(end + chip8.ProgramStart)]; ok {
+                break
+            }
+            end++
+        }
+        values := make([]string, 0, end - offset)
+        for _, b := range d.program[offset:end] {
+            values = append(values, fmt.Sprintf("0x%02X", b))
+        }
+        l.Lines = append(l.Lines, Line{
+            Address: addr,
+            Bytes:   d.program[offset:end],
+            Label:   d.labels[addr],
+            Text:    "db " + strings.Join(values, ", "),
+        })
+        offset = end
+    }
+    return l
+}
+
+// Format writes the listing as assembly source, annotated with the address
+// and bytes of every line
+func (l *Listing) Format(w io.Writer) error {
+    for _, line := range l.Lines {
+        if line.Label != "" {
+            if _, err := fmt.Fprintf(w, "%s:\n", line.Label); err != nil {
+                return err
+            }
+        }
+        if _, err := fmt.Fprintf(w, "    %-32s ; %03X: % X\n", line.Text, line.Address, line.Bytes); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (l *Listing) String() string {
+    var b strings.Builder
+    l.Format(&b)
+    return b.String()
+}
@@ -0,0 +1,112 @@
+package asm
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "math/rand"
+    "strings"
+    "testing"
+)
+
+func Test_Disassemble(t *testing.T) {
+    program := chip8.Build(
+        chip8.LDI(0x20A),
+        chip8.CALL(0x208),
+        chip8.JP(0x206),
+        chip8.JP(0x206),
+        chip8.RET(),
+        0xF090,
+        0x90F0,
+    )
+
+    listing := Disassemble(program)
+
+    expected := []Line{
+        {Address: 0x200, Text: "ld i, L20A", Code: true},
+        {Address: 0x202, Text: "call L208", Code: true},
+        {Address: 0x204, Text: "jp L206", Code: true},
+        {Address: 0x206, Label: "L206", Text: "jp L206", Code: true},
+        {Address: 0x208, Label: "L208", Text: "ret", Code: true},
+        {Address: 0x20A, Label: "L20A", Text: "db 0xF0, 0x90, 0x90, 0xF0"},
+    }
+    if len(listing.Lines) != len(expected) {
+        t.Fatalf("unexpected listing:\n%s", listing)
+    }
+    for i, line := range listing.Lines {
+        e := expected[i]
+        if line.Address != e.Address || line.Label != e.Label || line.Text != e.Text || line.Code != e.Code {
+            t.Errorf("unexpected line %d: %+v", i, line)
+        }
+    }
+}
+
+func Test_Disassemble_skips(t *testing.T) {
+    program := chip8.Build(
+        chip8.SE(0x1, 0x2),
+        chip8.RET(),
+        chip8.CLS(),
+        chip8.RET(),
+        0xFFFF,
+    )
+
+    listing := Disassemble(program)
+
+    for i, line := range listing.Lines {
+        if line.Code != (i < 4) {
+            t.Errorf("unexpected line %d: %+v", i, line)
+        }
+    }
+}
+
+func Test_Disassemble_format(t *testing.T) {
+    program := chip8.Build(
+        chip8.JP(0x200),
+    )
+
+    listing := Disassemble(program).String()
+
+    expected := "L200:\n    jp L200                          ; 200: 12 00\n"
+    if listing != expected {
+        t.Errorf("unexpected listing:\n%q", listing)
+    }
+}
+
+func Test_Disassemble_round_trip(t *testing.T) {
+    r := rand.New(rand.NewSource(1))
+    for i := 0; i < 100; i++ {
+        program := make([]byte, 2 + r.Intn(256))
+        r.Read(program)
+        // start with a few instructions, so the tracer has something to
+        // follow
+        for j := 0; j + 1 < len(program) && j < 16; j += 2 {
+            copy(program[j:], chip8.Build(chip8.CALL(uint16(0x200 + r.Intn(len(program))))))
+        }
+
+        source := Disassemble(program).String()
+        assembled, err := Assemble([]byte(source))
+        if err != nil {
+            t.Fatalf("listing does not assemble: %v\n%s", err, source)
+        }
+        if !bytes.Equal(assembled, program) {
+            t.Fatalf("listing does not round-trip:\n%s", source)
+        }
+    }
+}
+
+func Test_Disassemble_odd_label(t *testing.T) {
+    program := chip8.Build(
+        chip8.JP(0x203),
+        chip8.CALL(0x300),
+        chip8.RET(),
+    )
+
+    source := Disassemble(program).String()
+
+    if !strings.Contains(source, "L203:") {
+        t.Errorf("missing label:\n%s", source)
+    }
+    assembled, err := Assemble([]byte(source))
+    if err != nil || !bytes.Equal(assembled, program) {
+        t.Errorf("listing does not round-trip: %v\n%s", err, source)
+    }
+}
@@ -0,0 +1,301 @@
+package asm
+
+import (
+    "chip8-emulator/chip8"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// reserved contains the operand names other than the V registers, which can
+// not be used for labels or constants
+var reserved = map[string]bool{
+    "i":     true,
+    "[i]":   true,
+    "dt":    true,
+    "st":    true,
+    "k":     true,
+    "f":     true,
+    "b":     true,
+    "hf":    true,
+    "r":     true,
+    "pitch": true,
+    "long":  true,
+}
+
+// longAddress returns the address expression of the XO-CHIP "ld i, long
+// addr", which assembles to 4 bytes, and false for any other statement
+func longAddress(s *statement) (string, bool) {
+    if s.mnemonic != "ld" || len(s.operands) != 2 || !is(s.operands[0], "i") {
+        return "", false
+    }
+    fields := strings.Fields(s.operands[1])
+    if len(fields) < 2 || !is(fields[0], "long") {
+        return "", false
+    }
+    return strings.Join(fields[1:], " "), true
+}
+
+// parseRegister parses the name of a V register
+func parseRegister(op string) (uint16, bool) {
+    op = strings.ToLower(op)
+    if len(op) != 2 || op[0] != 'v' {
+        return 0, false
+    }
+    n, err := strconv.ParseUint(op[1:], 16, 8)
+    if err != nil {
+        return 0, false
+    }
+    return uint16(n), true
+}
+
+// register returns the V register named by op
+func register(op string) (uint16, error) {
+    if x, ok := parseRegister(op); ok {
+        return x, nil
+    }
+    return 0, fmt.Errorf("expected a register, got %q", op)
+}
+
+// isRegister returns true if op names a V register
+func isRegister(op string) bool {
+    _, ok := parseRegister(op)
+    return ok
+}
+
+// is returns true if op is the given special operand, such as I or DT
+func is(op, name string) bool {
+    return strings.EqualFold(op, name)
+}
+
+// encode returns the opcode of an instruction
+func (a *assembler) encode(s *statement) (uint16, error) {
+    ops := s.operands
+    count := func(n int) error {
+        if len(ops) != n {
+            return fmt.Errorf("%s expects %d operands, got %d", s.mnemonic, n, len(ops))
+        }
+        return nil
+    }
+    // registers returns the two V registers of an 8xyn style instruction
+    registers := func() (uint16, uint16, error) {
+        if err := count(2); err != nil {
+            return 0, 0, err
+        }
+        x, err := register(ops[0])
+        if err != nil {
+            return 0, 0, err
+        }
+        y, err := register(ops[1])
+        return x, y, err
+    }
+
+    switch s.mnemonic {
+    case "cls":
+        return chip8.CLS(), count(0)
+    case "ret":
+        return chip8.RET(), count(0)
+    case "scr":
+        return chip8.SCR(), count(0)
+    case "scl":
+        return chip8.SCL(), count(0)
+    case "exit":
+        return chip8.EXIT(), count(0)
+    case "low":
+        return chip8.LOW(), count(0)
+    case "high":
+        return chip8.HIGH(), count(0)
+    case "scd", "scu":
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        n, err := a.evalRange(ops[0], 0xF)
+        if s.mnemonic == "scu" {
+            return chip8.SCU(uint16(n)), err
+        }
+        return chip8.SCD(uint16(n)), err
+    case "save", "load":
+        x, y, err := registers()
+        if s.mnemonic == "save" {
+            return chip8.SAVE(x, y), err
+        }
+        return chip8.LOAD(x, y), err
+    case "plane":
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        n, err := a.evalRange(ops[0], 0xF)
+        return chip8.PLANE(uint16(n)), err
+    case "audio":
+        return chip8.AUDIO(), count(0)
+    case "sys":
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        addr, err := a.evalRange(ops[0], 0xFFF)
+        return uint16(addr), err
+    case "jp":
+        if len(ops) == 2 {
+            if !is(ops[0], "v0") {
+                return 0, fmt.Errorf("jp with two operands expects v0, got %q", ops[0])
+            }
+            addr, err := a.evalRange(ops[1], 0xFFF)
+            return chip8.JP_R(uint16(addr)), err
+        }
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        addr, err := a.evalRange(ops[0], 0xFFF)
+        return chip8.JP(uint16(addr)), err
+    case "call":
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        addr, err := a.evalRange(ops[0], 0xFFF)
+        return chip8.CALL(uint16(addr)), err
+    case "se", "sne":
+        if err := count(2); err != nil {
+            return 0, err
+        }
+        x, err := register(ops[0])
+        if err != nil {
+            return 0, err
+        }
+        if y, ok := parseRegister(ops[1]); ok {
+            if s.mnemonic == "se" {
+                return chip8.SE_R(x, y), nil
+            }
+            return chip8.SNE_R(x, y), nil
+        }
+        kk, err := a.evalRange(ops[1], 0xFF)
+        if s.mnemonic == "se" {
+            return chip8.SE(x, uint16(kk)), err
+        }
+        return chip8.SNE(x, uint16(kk)), err
+    case "ld":
+        if err := count(2); err != nil {
+            return 0, err
+        }
+        return a.encodeLoad(ops[0], ops[1])
+    case "add":
+        if err := count(2); err != nil {
+            return 0, err
+        }
+        if is(ops[0], "i") {
+            x, err := register(ops[1])
+            return chip8.ADD_I(x), err
+        }
+        x, err := register(ops[0])
+        if err != nil {
+            return 0, err
+        }
+        if y, ok := parseRegister(ops[1]); ok {
+            return chip8.ADD_R(x, y), nil
+        }
+        kk, err := a.evalRange(ops[1], 0xFF)
+        return chip8.ADD(x, uint16(kk)), err
+    case "or", "and", "xor", "sub", "subn":
+        x, y, err := registers()
+        builders := map[string]func(x, y uint16) uint16{
+            "or":   chip8.OR,
+            "and":  chip8.AND,
+            "xor":  chip8.XOR,
+            "sub":  chip8.SUB,
+            "subn": chip8.SUBN,
+        }
+        return builders[s.mnemonic](x, y), err
+    case "shr", "shl":
+        // the second register is optional, as it is only used by some
+        // interpreters
+        if len(ops) == 1 {
+            ops = append(ops, ops[0])
+        }
+        x, y, err := registers()
+        if s.mnemonic == "shr" {
+            return chip8.SHR(x) | y << 4, err
+        }
+        return chip8.SHL(x) | y << 4, err
+    case "rnd":
+        if err := count(2); err != nil {
+            return 0, err
+        }
+        x, err := register(ops[0])
+        if err != nil {
+            return 0, err
+        }
+        kk, err := a.evalRange(ops[1], 0xFF)
+        return chip8.RND(x, uint16(kk)), err
+    case "drw":
+        if err := count(3); err != nil {
+            return 0, err
+        }
+        x, err := register(ops[0])
+        if err != nil {
+            return 0, err
+        }
+        y, err := register(ops[1])
+        if err != nil {
+            return 0, err
+        }
+        n, err := a.evalRange(ops[2], 0xF)
+        return chip8.DRW(x, y, uint16(n)), err
+    case "skp", "sknp":
+        if err := count(1); err != nil {
+            return 0, err
+        }
+        x, err := register(ops[0])
+        if s.mnemonic == "skp" {
+            return chip8.SKP(x), err
+        }
+        return chip8.SKNP(x), err
+    }
+    return 0, fmt.Errorf("unknown instruction: %q", s.mnemonic)
+}
+
+// encodeLoad returns the opcode for the many forms of LD
+func (a *assembler) encodeLoad(dst, src string) (uint16, error) {
+    switch {
+    case is(dst, "i"):
+        addr, err := a.evalRange(src, 0xFFF)
+        return chip8.LDI(uint16(addr)), err
+    case is(dst, "dt"), is(dst, "st"), is(dst, "f"), is(dst, "b"), is(dst, "[i]"), is(dst, "hf"), is(dst, "r"), is(dst, "pitch"):
+        x, err := register(src)
+        switch strings.ToLower(dst) {
+        case "dt":
+            return chip8.LD_DT_VX(x), err
+        case "st":
+            return chip8.LD_ST_VX(x), err
+        case "f":
+            return chip8.LDF(x), err
+        case "b":
+            return chip8.LDB(x), err
+        case "hf":
+            return chip8.LD_HF_VX(x), err
+        case "r":
+            return chip8.LD_R_VX(x), err
+        case "pitch":
+            return chip8.LD_PITCH_VX(x), err
+        }
+        return chip8.LD_I_VX(x), err
+    }
+
+    x, err := register(dst)
+    if err != nil {
+        return 0, err
+    }
+    switch {
+    case isRegister(src):
+        y, _ := parseRegister(src)
+        return chip8.LD_R(x, y), nil
+    case is(src, "dt"):
+        return chip8.LD_VX_DT(x), nil
+    case is(src, "k"):
+        return chip8.LD_VX_K(x), nil
+    case is(src, "[i]"):
+        return chip8.LD_VX_I(x), nil
+    case is(src, "r"):
+        return chip8.LD_VX_R(x), nil
+    }
+    kk, err := a.evalRange(src, 0xFF)
+    return chip8.LD(x, uint16(kk)), err
+}
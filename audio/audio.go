@@ -0,0 +1,69 @@
+// Package audio turns the state of the CHIP-8 sound timer into a square wave
+// tone, or the XO-CHIP audio pattern, and writes it to an output backend.
+package audio
+
+const (
+    // DefaultSampleRate is the sample rate used by the front ends, in Hz
+    DefaultSampleRate = 44100
+    // DefaultPitch is the frequency of the tone, in Hz
+    DefaultPitch = 440
+    // DefaultVolume is the volume of the tone, between 0 and 1
+    DefaultVolume = 0.25
+)
+
+// Backend receives signed 16-bit mono samples
+type Backend interface {
+    WriteSamples(samples []int16) error
+    Close() error
+}
+
+// Null is a backend that discards all samples
+type Null struct{}
+
+func (Null) WriteSamples(samples []int16) error {
+    return nil
+}
+
+func (Null) Close() error {
+    return nil
+}
+
+// Player generates one frame of samples at a time and writes them to a
+// backend. Frames are expected at the 60 Hz timer frequency.
+type Player struct {
+    Generator *Generator
+    Backend   Backend
+    // FrameRate is the number of frames per second
+    FrameRate int
+
+    buffer    []int16
+    // remainder carries the samples that did not fit in the previous frame
+    remainder int
+}
+
+func NewPlayer(generator *Generator, backend Backend, frameRate int) *Player {
+    return &Player{
+        Generator: generator,
+        Backend:   backend,
+        FrameRate: frameRate,
+    }
+}
+
+// Frame writes a frame of samples, containing the tone if on is true and
+// silence otherwise
+func (p *Player) Frame(on bool) error {
+    samples := p.Generator.SampleRate + p.remainder
+    p.remainder = samples % p.FrameRate
+    n := samples / p.FrameRate
+    if cap(p.buffer) < n {
+        p.buffer = make([]int16, n)
+    }
+    p.buffer = p.buffer[:n]
+    p.Generator.Generate(p.buffer, on)
+    return p.Backend.WriteSamples(p.buffer)
+}
+
+// Close closes the backend
+func (p *Player) Close() error {
+    return p.Backend.Close()
+}
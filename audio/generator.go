@@ -0,0 +1,79 @@
+package audio
+
+import (
+    "math"
+)
+
+// Generator produces a square wave, or plays an XO-CHIP audio pattern. The
+// phase is kept between calls to Generate, so consecutive buffers form a
+// continuous signal.
+type Generator struct {
+    SampleRate int
+    // Pitch is the frequency of the tone, in Hz
+    Pitch float64
+    // Volume is the amplitude of the tone, between 0 and 1
+    Volume float64
+    // Muted silences the tone without stopping the generator
+    Muted bool
+    // Pattern, if set, is played instead of the square wave, one bit at a
+    // time starting with the highest bit of the first byte, and repeated
+    Pattern []byte
+    // PatternRate is the rate at which the bits of Pattern are played, in
+    // bits per second
+    PatternRate float64
+
+    phase float64
+}
+
+func NewGenerator(sampleRate int) *Generator {
+    return &Generator{
+        SampleRate: sampleRate,
+        Pitch:      DefaultPitch,
+        Volume:     DefaultVolume,
+    }
+}
+
+// Generate fills buf with the tone if on is true, and with silence otherwise
+func (g *Generator) Generate(buf []int16, on bool) {
+    if !on || g.Muted || g.Volume <= 0 {
+        for i := range buf {
+            buf[i] = 0
+        }
+        // restart the wave at the next tone, so it always starts the same
+        g.phase = 0
+        return
+    }
+
+    amplitude := int16(math.Min(g.Volume, 1) * math.MaxInt16)
+    if len(g.Pattern) > 0 {
+        g.generatePattern(buf, amplitude)
+        return
+    }
+    step := g.Pitch / float64(g.SampleRate)
+    for i := range buf {
+        if g.phase < 0.5 {
+            buf[i] = amplitude
+        } else {
+            buf[i] = -amplitude
+        }
+        g.phase += step
+        g.phase -= math.Floor(g.phase)
+    }
+}
+
+// generatePattern fills buf with the pattern. The phase counts the bits of
+// the pattern played.
+func (g *Generator) generatePattern(buf []int16, amplitude int16) {
+    bits := float64(len(g.Pattern) * 8)
+    step := g.PatternRate / float64(g.SampleRate)
+    g.phase = math.Mod(g.phase, bits)
+    for i := range buf {
+        bit := int(g.phase)
+        if g.Pattern[bit / 8] & (0x80 >> (bit % 8)) != 0 {
+            buf[i] = amplitude
+        } else {
+            buf[i] = -amplitude
+        }
+        g.phase = math.Mod(g.phase + step, bits)
+    }
+}
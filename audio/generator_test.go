@@ -0,0 +1,100 @@
+package audio
+
+import (
+    "testing"
+)
+
+func Test_Generate_silence(t *testing.T) {
+    g := NewGenerator(8000)
+    buf := []int16{1, 2, 3, 4}
+
+    g.Generate(buf, false)
+
+    for i, s := range buf {
+        if s != 0 {
+            t.Errorf("sample %d should be silent: %v", i, s)
+        }
+    }
+}
+
+func Test_Generate_square_wave(t *testing.T) {
+    g := NewGenerator(8000)
+    g.Pitch = 1000
+    g.Volume = 0.5
+    buf := make([]int16, 16)
+
+    g.Generate(buf, true)
+
+    // 8 samples per period, 4 high followed by 4 low
+    for i, s := range buf {
+        expected := int16(16383)
+        if i % 8 >= 4 {
+            expected = -expected
+        }
+        if s != expected {
+            t.Errorf("unexpected sample %d: %v", i, s)
+        }
+    }
+}
+
+func Test_Generate_continuous(t *testing.T) {
+    g := NewGenerator(8000)
+    g.Pitch = 1000
+    a := make([]int16, 6)
+    b := make([]int16, 6)
+    whole := make([]int16, 12)
+
+    g.Generate(a, true)
+    g.Generate(b, true)
+    reference := NewGenerator(8000)
+    reference.Pitch = 1000
+    reference.Generate(whole, true)
+
+    for i := range whole {
+        var s int16
+        if i < 6 {
+            s = a[i]
+        } else {
+            s = b[i - 6]
+        }
+        if s != whole[i] {
+            t.Errorf("wave is not continuous at sample %d: %v != %v", i, s, whole[i])
+        }
+    }
+}
+
+func Test_Generate_muted(t *testing.T) {
+    g := NewGenerator(8000)
+    g.Muted = true
+    buf := make([]int16, 8)
+
+    g.Generate(buf, true)
+
+    for i, s := range buf {
+        if s != 0 {
+            t.Errorf("sample %d should be silent: %v", i, s)
+        }
+    }
+}
+
+func Test_Generate_pattern(t *testing.T) {
+    g := NewGenerator(8000)
+    g.Volume = 0.5
+    g.Pattern = []byte{0xA0, 0x01}
+    g.PatternRate = 4000
+    buf := make([]int16, 40)
+
+    g.Generate(buf, true)
+
+    // 2 samples per bit, and the pattern repeats after 16 bits
+    for i, s := range buf {
+        bit := i / 2 % 16
+        expected := int16(-16383)
+        if bit == 0 || bit == 2 || bit == 15 {
+            expected = -expected
+        }
+        if s != expected {
+            t.Errorf("unexpected sample %d: %v", i, s)
+        }
+    }
+}
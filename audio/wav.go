@@ -0,0 +1,93 @@
+package audio
+
+import (
+    "encoding/binary"
+    "io"
+    "os"
+)
+
+// wavHeaderSize is the size of a canonical RIFF/WAVE header for PCM data
+const wavHeaderSize = 44
+
+// WAV is a backend that writes 16-bit mono PCM to a WAV file, for use on
+// machines without sound hardware
+type WAV struct {
+    w          io.WriteSeeker
+    sampleRate int
+    dataSize   uint32
+    buffer     []byte
+}
+
+// NewWAV writes a WAV header to w and returns a backend that writes samples
+// after it. The sizes in the header are filled in by Close.
+func NewWAV(w io.WriteSeeker, sampleRate int) (*WAV, error) {
+    wav := &WAV{w: w, sampleRate: sampleRate}
+    if err := wav.writeHeader(); err != nil {
+        return nil, err
+    }
+    return wav, nil
+}
+
+// CreateWAV creates the named file and returns a WAV backend writing to it.
+// Closing the backend closes the file.
+func CreateWAV(path string, sampleRate int) (*WAV, error) {
+    f, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    wav, err := NewWAV(f, sampleRate)
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    return wav, nil
+}
+
+func (w *WAV) WriteSamples(samples []int16) error {
+    if cap(w.buffer) < len(samples) * 2 {
+        w.buffer = make([]byte, len(samples) * 2)
+    }
+    w.buffer = w.buffer[:len(samples) * 2]
+    for i, s := range samples {
+        binary.LittleEndian.PutUint16(w.buffer[i * 2:], uint16(s))
+    }
+    n, err := w.w.Write(w.buffer)
+    w.dataSize += uint32(n)
+    return err
+}
+
+// Close updates the header with the final sizes, and closes the underlying
+// writer if it is an io.Closer
+func (w *WAV) Close() error {
+    if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    err := w.writeHeader()
+    if c, ok := w.w.(io.Closer); ok {
+        if cerr := c.Close(); err == nil {
+            err = cerr
+        }
+    }
+    return err
+}
+
+func (w *WAV) writeHeader() error {
+    const channels = 1
+    const bitsPerSample = 16
+    header := make([]byte, wavHeaderSize)
+    copy(header[0:], "RIFF")
+    binary.LittleEndian.PutUint32(header[4:], 36 + w.dataSize)
+    copy(header[8:], "WAVE")
+    copy(header[12:], "fmt ")
+    binary.LittleEndian.PutUint32(header[16:], 16)
+    binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+    binary.LittleEndian.PutUint16(header[22:], channels)
+    binary.LittleEndian.PutUint32(header[24:], uint32(w.sampleRate))
+    binary.LittleEndian.PutUint32(header[28:], uint32(w.sampleRate * channels * bitsPerSample / 8))
+    binary.LittleEndian.PutUint16(header[32:], channels * bitsPerSample / 8)
+    binary.LittleEndian.PutUint16(header[34:], bitsPerSample)
+    copy(header[36:], "data")
+    binary.LittleEndian.PutUint32(header[40:], w.dataSize)
+    _, err := w.w.Write(header)
+    return err
+}
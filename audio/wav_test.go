@@ -0,0 +1,57 @@
+package audio
+
+import (
+    "encoding/binary"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func Test_WAV(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wav")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    path := filepath.Join(dir, "out.wav")
+    wav, err := CreateWAV(path, 600)
+    if err != nil {
+        t.Fatal(err)
+    }
+    p := NewPlayer(NewGenerator(600), wav, 60)
+
+    if err := p.Frame(true); err != nil {
+        t.Fatal(err)
+    }
+    if err := p.Frame(false); err != nil {
+        t.Fatal(err)
+    }
+    if err := p.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(data) != wavHeaderSize + 2 * 10 * 2 {
+        t.Fatalf("unexpected file size: %d", len(data))
+    }
+    if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+        t.Error("missing RIFF/WAVE header")
+    }
+    if size := binary.LittleEndian.Uint32(data[40:]); size != 40 {
+        t.Errorf("unexpected data size: %d", size)
+    }
+    if rate := binary.LittleEndian.Uint32(data[24:]); rate != 600 {
+        t.Errorf("unexpected sample rate: %d", rate)
+    }
+    if s := int16(binary.LittleEndian.Uint16(data[wavHeaderSize:])); s <= 0 {
+        t.Errorf("first frame should contain the tone: %d", s)
+    }
+    if s := int16(binary.LittleEndian.Uint16(data[wavHeaderSize + 20:])); s != 0 {
+        t.Errorf("second frame should be silent: %d", s)
+    }
+}
@@ -0,0 +1,319 @@
+package chip8
+
+// Executor executes the instructions of a CPU
+type Executor interface {
+    // Run executes up to n instructions, stopping at the first error. It
+    // returns the number of instructions executed without error.
+    Run(n int) (int, error)
+}
+
+// Run executes up to n instructions with Cycle, stopping at the first error.
+// It returns the number of instructions executed without error.
+func (c *CPU) Run(n int) (int, error) {
+    for i := 0; i < n; i++ {
+        if err := c.Cycle(); err != nil {
+            return i, err
+        }
+    }
+    return n, nil
+}
+
+// maxBlockLength is the largest number of instructions in a block
+const maxBlockLength = 64
+
+// microOp is a decoded instruction. The register and timer instructions
+// that can not fail are executed inline, selected by op, and the others with
+// the handler.
+type microOp struct {
+    op      Op
+    inline  bool
+    x, y    byte
+    nn      byte
+    nnn     uint16
+    handler func(c *CPU, in *Instruction) error
+    in      *Instruction
+    // writes is set for the instructions that store to memory at the index
+    writes  bool
+}
+
+// block is a run of instructions starting at start and ending before end.
+// Only the last instruction may change the control flow or write memory, so
+// the others always run in sequence.
+type block struct {
+    start int
+    end   int
+    ops   []microOp
+}
+
+// BlockCache executes a CPU like Cycle, but decodes the program into blocks
+// of instructions once and then runs them from a cache, which is faster for
+// long runs.
+//
+// Blocks that are overwritten by Fx55, Fx33 or 5xy2 are dropped from the
+// cache, as are all blocks when memory is replaced by Initialize,
+// LoadProgram or ReadState. Invalidate must be called after writing to
+// Memory in any other way. When the CPU has a Tracer the instructions are
+// executed with Cycle.
+type BlockCache struct {
+    CPU *CPU
+
+    blocks [MemorySize]*block
+    // cached lists the blocks in the cache, and covered counts the blocks
+    // that contain each address. Blocks are at most 2 * maxBlockLength
+    // bytes, so no more than 128 contain the same address.
+    cached  []*block
+    covered [MemorySize]byte
+    // handlers and memoryVersion are those of the CPU the blocks were
+    // decoded for, as the quirks select the handlers and the memory version
+    // changes when the program is replaced
+    handlers      *[opCount]func(c *CPU, in *Instruction) error
+    memoryVersion uint64
+}
+
+func NewBlockCache(cpu *CPU) *BlockCache {
+    return &BlockCache{
+        CPU:           cpu,
+        handlers:      cpu.handlers(),
+        memoryVersion: cpu.memoryVersion,
+    }
+}
+
+// Run executes up to n instructions, stopping at the first error, with the
+// same results as calling Cycle n times. The input is only polled once, so
+// the results only match if it does not change during the call, which is
+// the case for front ends that read the input once per frame. It returns
+// the number of instructions executed without error.
+func (b *BlockCache) Run(n int) (int, error) {
+    c := b.CPU
+    if c.Tracer != nil {
+        return c.Run(n)
+    }
+    if b.handlers != c.handlers() || b.memoryVersion != c.memoryVersion {
+        b.Invalidate()
+        b.handlers = c.handlers()
+        b.memoryVersion = c.memoryVersion
+    }
+    c.pollInput()
+    done := 0
+    for done < n {
+        pc := c.ProgramCounter
+        blk := b.blocks[pc]
+        if blk == nil {
+            if !c.inMemory(pc, 2) {
+                return done, c.fault(ErrPCOutOfBounds, 0)
+            }
+            blk = b.decode(pc)
+        }
+        ops := blk.ops
+        if len(ops) > n - done {
+            ops = ops[:n - done]
+        }
+        i, err := b.run(blk, ops)
+        done += i
+        if err != nil {
+            return done, err
+        }
+    }
+    return done, nil
+}
+
+// run executes the ops at the start of a block, and returns the number
+// executed without error. The program counter is only set for the ops run
+// by their handler, and after the last op.
+func (b *BlockCache) run(blk *block, ops []microOp) (int, error) {
+    c := b.CPU
+    r := &c.Register
+    for i := range ops {
+        op := &ops[i]
+        if !op.inline {
+            c.ProgramCounter = uint16(blk.start + i * 2)
+            index := c.Index
+            if err := op.handler(c, op.in); err != nil {
+                return i, err
+            }
+            c.ProgramCounter += 2
+            if op.writes {
+                b.written(int(index), writeSize(op.in))
+            }
+            continue
+        }
+        switch op.op {
+        case OpLD:
+            r[op.x] = op.nn
+        case OpADD:
+            r[op.x] += op.nn
+        case OpLD_R:
+            r[op.x] = r[op.y]
+        case OpOR, OpAND, OpXOR:
+            switch op.op {
+            case OpOR:
+                r[op.x] |= r[op.y]
+            case OpAND:
+                r[op.x] &= r[op.y]
+            default:
+                r[op.x] ^= r[op.y]
+            }
+            if c.Quirks.ResetVF {
+                r[0xF] = 0
+            }
+        case OpADD_R:
+            sum := uint16(r[op.x]) + uint16(r[op.y])
+            r[op.x] = byte(sum)
+            r[0xF] = byte(sum >> 8)
+        case OpSUB:
+            x, y := r[op.x], r[op.y]
+            r[op.x] = x - y
+            r[0xF] = flag(x >= y)
+        case OpSUBN:
+            x, y := r[op.x], r[op.y]
+            r[op.x] = y - x
+            r[0xF] = flag(y >= x)
+        case OpSHR:
+            value := c.shiftSource(uint16(op.x), uint16(op.y))
+            r[op.x] = value >> 1
+            r[0xF] = value & 0x1
+        case OpSHL:
+            value := c.shiftSource(uint16(op.x), uint16(op.y))
+            r[op.x] = value << 1
+            r[0xF] = value >> 7
+        case OpLDI:
+            c.Index = op.nnn
+        case OpADD_I:
+            c.Index += uint16(r[op.x])
+        case OpLDF:
+            c.Index = uint16(r[op.x] & 0xF) * 5
+        case OpLD_VX_DT:
+            r[op.x] = c.DelayTimer
+        case OpLD_DT_VX:
+            c.DelayTimer = r[op.x]
+        case OpLD_ST_VX:
+            c.SoundTimer = r[op.x]
+        }
+    }
+    if last := len(ops) - 1; last >= 0 && ops[last].inline {
+        c.ProgramCounter = uint16(blk.start + len(ops) * 2)
+    }
+    return len(ops), nil
+}
+
+// Invalidate drops all blocks from the cache
+func (b *BlockCache) Invalidate() {
+    for _, blk := range b.cached {
+        b.drop(blk)
+    }
+    b.cached = b.cached[:0]
+}
+
+// decode decodes the block starting at start and adds it to the cache
+func (b *BlockCache) decode(start uint16) *block {
+    c := b.CPU
+    blk := &block{start: int(start), end: int(start)}
+    size := c.memorySize()
+    for blk.end + 2 <= size && len(blk.ops) < maxBlockLength {
+        in := &instructions[c.word(uint16(blk.end))]
+        blk.ops = append(blk.ops, microOp{
+            op:      in.Op,
+            inline:  inline(in.Op),
+            x:       in.X,
+            y:       in.Y,
+            nn:      in.NN,
+            nnn:     in.NNN,
+            handler: b.handlers[in.Op],
+            in:      in,
+            writes:  writeSize(in) > 0,
+        })
+        blk.end += 2
+        if endsBlock(in.Op) {
+            break
+        }
+    }
+    b.blocks[start] = blk
+    b.cached = append(b.cached, blk)
+    for addr := blk.start; addr < blk.end; addr++ {
+        b.covered[addr]++
+    }
+    return blk
+}
+
+// written drops the blocks that contain any of the n bytes of memory
+// starting at addr
+func (b *BlockCache) written(addr, n int) {
+    hit := false
+    for i := addr; i < addr + n && i < MemorySize; i++ {
+        if b.covered[i] > 0 {
+            hit = true
+            break
+        }
+    }
+    if !hit {
+        return
+    }
+    kept := b.cached[:0]
+    for _, blk := range b.cached {
+        if blk.start < addr + n && addr < blk.end {
+            b.drop(blk)
+        } else {
+            kept = append(kept, blk)
+        }
+    }
+    b.cached = kept
+}
+
+// drop removes a block from the blocks and covered tables, but not from the
+// cached list
+func (b *BlockCache) drop(blk *block) {
+    b.blocks[blk.start] = nil
+    for addr := blk.start; addr < blk.end; addr++ {
+        b.covered[addr]--
+    }
+}
+
+// endsBlock returns true for the instructions that may change the control
+// flow, wait, or write memory, after which a block ends. The XO-CHIP F000
+// ends it too, as it is 4 bytes long.
+func endsBlock(op Op) bool {
+    switch op {
+    case OpInvalid, OpRET, OpEXIT, OpJP, OpCALL, OpJP_R,
+        OpSE, OpSNE, OpSE_R, OpSNE_R, OpSKP, OpSKNP,
+        OpLD_I_LONG, OpLD_VX_K, OpSAVE, OpLDB, OpLD_I_VX:
+        return true
+    }
+    return false
+}
+
+// inline returns true for the instructions executed inline by run. They only
+// change the registers, the index and the timers, and have the same handler
+// in every handler table.
+func inline(op Op) bool {
+    switch op {
+    case OpLD, OpADD, OpLD_R, OpOR, OpAND, OpXOR, OpADD_R, OpSUB, OpSUBN,
+        OpSHR, OpSHL, OpLDI, OpADD_I, OpLDF, OpLD_VX_DT, OpLD_DT_VX, OpLD_ST_VX:
+        return true
+    }
+    return false
+}
+
+// flag returns the value of VF for a condition
+func flag(set bool) byte {
+    if set {
+        return 1
+    }
+    return 0
+}
+
+// writeSize returns the number of bytes an instruction stores at the index,
+// or 0 if it does not write memory
+func writeSize(in *Instruction) int {
+    switch in.Op {
+    case OpLD_I_VX:
+        return int(in.X) + 1
+    case OpLDB:
+        return 3
+    case OpSAVE:
+        if in.X > in.Y {
+            return int(in.X - in.Y) + 1
+        }
+        return int(in.Y - in.X) + 1
+    }
+    return 0
+}
@@ -0,0 +1,300 @@
+package chip8
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+)
+
+// frameInput holds the keys pressed during a frame, which compareRuns
+// changes at random between the runs
+type frameInput struct {
+    keys [KeyCount]bool
+}
+
+func (i *frameInput) KeyState() [KeyCount]bool {
+    return i.keys
+}
+
+// randomProgram returns a program of valid instructions, with jumps and
+// calls into the program and the index mostly pointing into the program, so
+// it runs for a while and overwrites its own code
+func randomProgram(random *XorShift, quirks Quirks, size int) []uint16 {
+    ops := make([]uint16, 0, size)
+    for len(ops) < size {
+        opCode := uint16(random.RandomByte()) << 8 | uint16(random.RandomByte())
+        in := Decode(opCode)
+        if in.Op == OpInvalid || in.Op.XOChip() && !quirks.XOChip || in.Op == OpEXIT {
+            continue
+        }
+        target := ProgramStart + uint16(random.RandomByte()) % uint16(size) * 2
+        switch in.Op {
+        case OpJP, OpCALL, OpJP_R:
+            opCode = opCode & 0xF000 | target
+        case OpLDI:
+            opCode = opCode & 0xF000 | target + uint16(random.RandomByte()) % 8
+        }
+        ops = append(ops, opCode)
+    }
+    return ops
+}
+
+// newDifferentialCPUs returns two CPUs in the same state, with the same
+// random source
+func newDifferentialCPUs(t *testing.T, quirks Quirks, seed int64, ops ...uint16) (*CPU, *CPU) {
+    t.Helper()
+    cpus := [2]*CPU{}
+    for i := range cpus {
+        c, err := NewCPU(Build(ops...))
+        if err != nil {
+            t.Fatal(err)
+        }
+        c.Quirks = quirks
+        c.Seed(seed)
+        c.Input = &frameInput{}
+        cpus[i] = c
+    }
+    return cpus[0], cpus[1]
+}
+
+// sameState returns true if two CPUs are in the same machine state, including
+// their random sources
+func sameState(a, b *CPU) bool {
+    return a.Memory == b.Memory &&
+        a.Register == b.Register &&
+        a.Index == b.Index &&
+        a.DelayTimer == b.DelayTimer &&
+        a.SoundTimer == b.SoundTimer &&
+        a.ProgramCounter == b.ProgramCounter &&
+        a.StackPointer == b.StackPointer &&
+        a.Stack == b.Stack &&
+        a.DisplayBuffer == b.DisplayBuffer &&
+        a.HighRes == b.HighRes &&
+        a.Planes == b.Planes &&
+        a.AudioPattern == b.AudioPattern &&
+        a.Pitch == b.Pitch &&
+        a.Keypad == b.Keypad &&
+        a.Flags == b.Flags &&
+        a.keyHeld == b.keyHeld &&
+        a.heldKey == b.heldKey &&
+        *a.Random.(*XorShift) == *b.Random.(*XorShift)
+}
+
+// compareRuns runs the interpreter and a BlockCache side by side in steps of
+// random length, with random keys pressed during each step, and fails at the
+// first difference in their results or machine state. It returns the number
+// of instructions executed.
+func compareRuns(t *testing.T, interpreter *CPU, cache *BlockCache, steps *XorShift, cycles int) int {
+    t.Helper()
+    total := 0
+    for total < cycles {
+        bits := uint16(steps.RandomByte()) << 8 | uint16(steps.RandomByte())
+        for _, c := range []*CPU{interpreter, cache.CPU} {
+            input := c.Input.(*frameInput)
+            for k := range input.keys {
+                input.keys[k] = bits & (1 << k) != 0
+            }
+        }
+        n := int(steps.RandomByte()) % 100 + 1
+        expectedDone, expectedErr := interpreter.Run(n)
+        done, err := cache.Run(n)
+        if done != expectedDone || fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+            t.Fatalf("after %d instructions: got %d, %v, expected %d, %v", total, done, err, expectedDone, expectedErr)
+        }
+        if !sameState(interpreter, cache.CPU) {
+            t.Fatalf("after %d instructions: states differ at pc %03X", total + done, interpreter.ProgramCounter)
+        }
+        total += done
+        if err != nil {
+            break
+        }
+    }
+    return total
+}
+
+func Test_BlockCache_differential(t *testing.T) {
+    for _, name := range QuirksProfiles() {
+        quirks, _ := QuirksProfile(name)
+        t.Run(name, func(t *testing.T) {
+            executed := 0
+            for seed := int64(1); seed <= 200; seed++ {
+                random := NewXorShift(seed)
+                interpreter, c := newDifferentialCPUs(t, quirks, seed, randomProgram(random, quirks, 64)...)
+                executed += compareRuns(t, interpreter, NewBlockCache(c), random, 5000)
+            }
+            if executed < 100000 {
+                t.Errorf("the programs ended too soon: %d instructions", executed)
+            }
+        })
+    }
+}
+
+// blockBenchmarkProgram is a loop of register instructions, like the inner loops
+// of most programs
+var blockBenchmarkProgram = []uint16{
+    LD(0x0, 0x12),
+    ADD(0x1, 0x03),
+    LD_R(0x2, 0x1),
+    ADD_R(0x2, 0x0),
+    SUB(0x3, 0x2),
+    XOR(0x3, 0x1),
+    SHR(0x3),
+    SE(0x4, 0xFF),
+    JP(0x200),
+}
+
+// Benchmark_BlockCache_speedup runs the benchmark program with the
+// interpreter and the block cache, and reports how many times faster the
+// block cache is
+func Benchmark_BlockCache_speedup(b *testing.B) {
+    run := func(executor func(c *CPU) Executor) time.Duration {
+        e := executor(NewTestCPU(blockBenchmarkProgram...))
+        start := time.Now()
+        if _, err := e.Run(b.N); err != nil {
+            b.Fatal(err)
+        }
+        return time.Since(start)
+    }
+    interpreter := run(func(c *CPU) Executor { return c })
+    blocks := run(func(c *CPU) Executor { return NewBlockCache(c) })
+    b.ReportMetric(float64(interpreter) / float64(blocks), "speedup")
+}
+
+func Test_BlockCache_selfModifying(t *testing.T) {
+    for _, test := range []struct {
+        name string
+        ops  []uint16
+    }{
+        // rewrites the first instruction to add 2 instead of 1
+        {"LD_I_VX", []uint16{
+            ADD(0x5, 0x01),
+            LDI(0x200),
+            LD(0x0, 0x75),
+            LD(0x1, 0x02),
+            LD_I_VX(0x1),
+            JP(0x200),
+        }},
+        // rewrites the first two instructions of its own block with the
+        // digits 1, 1, 7, which turns them into 0101 and 0775
+        {"LDB", []uint16{
+            ADD(0x5, 0x01),
+            LD(0x0, 117),
+            LDI(0x200),
+            LDB(0x0),
+            JP(0x200),
+        }},
+        {"SAVE", []uint16{
+            ADD(0x5, 0x01),
+            LDI(0x200),
+            LD(0x0, 0x75),
+            LD(0x1, 0x02),
+            SAVE(0x0, 0x1),
+            JP(0x200),
+        }},
+    } {
+        t.Run(test.name, func(t *testing.T) {
+            interpreter, c := newDifferentialCPUs(t, QuirksXOChip, 1, test.ops...)
+            cache := NewBlockCache(c)
+            compareRuns(t, interpreter, cache, NewXorShift(1), 1000)
+
+            if c.Register[0x5] == 0 || c.Register[0x5] % 2 != 1 {
+                t.Errorf("unexpected register: %v", c.Register[0x5])
+            }
+        })
+    }
+}
+
+func Test_BlockCache_ReadState(t *testing.T) {
+    c := NewTestCPU(
+        ADD(0x5, 0x01),
+        JP(0x200),
+    )
+    cache := NewBlockCache(c)
+    cache.Run(10)
+
+    other := NewTestCPU(
+        ADD(0x5, 0x02),
+        JP(0x200),
+    )
+    state, _ := other.MarshalBinary()
+    if err := c.UnmarshalBinary(state); err != nil {
+        t.Fatal(err)
+    }
+    cache.Run(10)
+
+    if c.Register[0x5] != 10 {
+        t.Errorf("the restored program was not decoded again: %v", c.Register[0x5])
+    }
+}
+
+func Test_BlockCache_Invalidate(t *testing.T) {
+    c := NewTestCPU(
+        ADD(0x5, 0x01),
+        JP(0x200),
+    )
+    cache := NewBlockCache(c)
+    cache.Run(2)
+
+    c.Memory[0x201] = 0x02
+    cache.Invalidate()
+    cache.Run(2)
+
+    if c.Register[0x5] != 3 {
+        t.Errorf("unexpected register: %v", c.Register[0x5])
+    }
+}
+
+func Test_BlockCache_errors(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x0, 0x01),
+        0x5001,
+    )
+    cache := NewBlockCache(c)
+
+    done, err := cache.Run(10)
+
+    if done != 1 || !errors.Is(err, ErrUnknownOpcode) {
+        t.Errorf("unexpected result: %d, %v", done, err)
+    }
+    if c.ProgramCounter != 0x202 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+
+    c.ProgramCounter = ClassicMemorySize
+    if done, err := cache.Run(10); done != 0 || !errors.Is(err, ErrPCOutOfBounds) {
+        t.Errorf("unexpected result: %d, %v", done, err)
+    }
+}
+
+func Test_Scheduler_Executor(t *testing.T) {
+    c := NewTestCPU(
+        ADD(0x1, 0x1),
+        JP(0x200),
+    )
+    s := NewScheduler(c, 600)
+    s.Executor = NewBlockCache(c)
+
+    s.Frame()
+
+    if c.Register[0x1] != 5 {
+        t.Errorf("expected 10 cycles per frame, got %v additions", c.Register[0x1])
+    }
+}
+
+func benchmarkExecutor(b *testing.B, executor func(c *CPU) Executor) {
+    c := NewTestCPU(blockBenchmarkProgram...)
+    e := executor(c)
+    b.ResetTimer()
+    if _, err := e.Run(b.N); err != nil {
+        b.Fatal(err)
+    }
+}
+
+func Benchmark_Run_interpreter(b *testing.B) {
+    benchmarkExecutor(b, func(c *CPU) Executor { return c })
+}
+
+func Benchmark_Run_blocks(b *testing.B) {
+    benchmarkExecutor(b, func(c *CPU) Executor { return NewBlockCache(c) })
+}
@@ -0,0 +1,53 @@
+package chip8
+
+// TimerFrequency is the rate in Hz at which the delay and sound timers count
+// down, independent of the clock speed
+const TimerFrequency = 60
+
+// DefaultClockSpeed is the default number of instructions executed per second
+const DefaultClockSpeed = 600
+
+// Scheduler runs a CPU at a fixed clock speed in frames of 1/60th of a
+// second, ticking the timers once per frame.
+type Scheduler struct {
+    CPU *CPU
+    // ClockSpeed is the number of instructions executed per second
+    ClockSpeed int
+    // Executor executes the instructions, or the CPU itself if it is nil
+    Executor Executor
+
+    // remainder carries the cycles that did not fit in the previous frame,
+    // so clock speeds that are not a multiple of 60 Hz stay accurate
+    remainder int
+}
+
+func NewScheduler(cpu *CPU, clockSpeed int) *Scheduler {
+    return &Scheduler{
+        CPU:        cpu,
+        ClockSpeed: clockSpeed,
+    }
+}
+
+// FrameCycles returns the number of instructions in the next frame, and
+// advances the scheduler to the frame after it. It is used by front ends that
+// execute the instructions themselves.
+func (s *Scheduler) FrameCycles() int {
+    cycles := s.ClockSpeed + s.remainder
+    s.remainder = cycles % TimerFrequency
+    return cycles / TimerFrequency
+}
+
+// Frame executes one frame worth of instructions and then ticks the timers.
+// Execution stops at the first instruction that returns an error, without
+// ticking the timers.
+func (s *Scheduler) Frame() error {
+    executor := s.Executor
+    if executor == nil {
+        executor = s.CPU
+    }
+    if _, err := executor.Run(s.FrameCycles()); err != nil {
+        return err
+    }
+    s.CPU.TickTimers()
+    return nil
+}
@@ -0,0 +1,45 @@
+package chip8
+
+import (
+    "testing"
+)
+
+func Test_Scheduler_Frame(t *testing.T) {
+    c := NewTestCPU(
+        JP(0x200),
+    )
+    c.DelayTimer = 10
+    s := NewScheduler(c, 90)
+
+    s.Frame()
+
+    if c.DelayTimer != 9 {
+        t.Errorf("timers should tick once per frame: %v", c.DelayTimer)
+    }
+    if s.remainder != 30 {
+        t.Errorf("unexpected remainder: %v", s.remainder)
+    }
+
+    s.Frame()
+
+    if c.DelayTimer != 8 {
+        t.Errorf("timers should tick once per frame: %v", c.DelayTimer)
+    }
+    if s.remainder != 0 {
+        t.Errorf("unexpected remainder: %v", s.remainder)
+    }
+}
+
+func Test_Scheduler_ClockSpeed(t *testing.T) {
+    c := NewTestCPU(
+        ADD(0x1, 0x1),
+        JP(0x200),
+    )
+    s := NewScheduler(c, 600)
+
+    s.Frame()
+
+    if c.Register[0x1] != 5 {
+        t.Errorf("expected 10 cycles per frame, got %v additions", c.Register[0x1])
+    }
+}
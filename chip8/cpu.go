@@ -0,0 +1,185 @@
+// Package chip8 implements the CHIP-8 virtual machine: memory, registers,
+// timers, keypad and display, independent of any front end.
+package chip8
+
+import (
+    "errors"
+    "fmt"
+    "time"
+)
+
+const (
+    // MemorySize is the size of the XO-CHIP address space in bytes
+    MemorySize = 0x10000
+    // ClassicMemorySize is the size of the address space in bytes when
+    // XO-CHIP is not enabled
+    ClassicMemorySize = 0x1000
+    // ProgramStart is the address programs are loaded at
+    ProgramStart = 0x200
+    // MaxProgramSize is the largest program that fits in memory with
+    // XO-CHIP enabled
+    MaxProgramSize = MemorySize - ProgramStart
+    // ClassicMaxProgramSize is the largest program that fits in memory when
+    // XO-CHIP is not enabled
+    ClassicMaxProgramSize = ClassicMemorySize - ProgramStart
+)
+
+// ErrProgramTooLarge is returned when a program does not fit in memory
+var ErrProgramTooLarge = errors.New("program too large")
+
+// CPU holds the complete state of a CHIP-8 machine
+type CPU struct {
+    Memory [MemorySize]byte
+    Register [16]byte
+    Index uint16
+    DelayTimer byte
+    SoundTimer byte
+    ProgramCounter uint16
+    StackPointer byte
+    DisplayBuffer [HiResWidth][HiResHeight]byte
+    // HighRes is set when the SUPER-CHIP high resolution mode is active
+    HighRes bool
+    // Planes is the bitmask of the XO-CHIP planes selected by Fn01
+    Planes byte
+    // AudioPattern and Pitch are the XO-CHIP audio pattern, loaded by F002,
+    // and its playback rate set by Fx3A
+    AudioPattern [PatternSize]byte
+    Pitch byte
+    Stack [16]uint16
+    Keypad [KeyCount]bool
+    Input InputSource
+    Random RandomSource
+    Quirks Quirks
+    // Flags are the RPL user flags of Fx75 and Fx85, which are persisted in
+    // the FlagStore if one is set
+    Flags [FlagCount]byte
+    FlagStore FlagStore
+    // Tracer, if set, is called after every instruction
+    Tracer Tracer
+
+    // keyHeld and heldKey track the key pressed while Fx0A is waiting for it
+    // to be released
+    keyHeld bool
+    heldKey byte
+    // memoryVersion changes whenever memory is replaced as a whole, so a
+    // BlockCache knows to drop its blocks
+    memoryVersion uint64
+}
+
+// fontSet contains the built-in hexadecimal font, 5 bytes per character,
+// which is loaded at the start of memory
+var fontSet = []byte {
+    0xF0,0x90,0x90,0x90,0xF0, // "0"
+    0x20,0x60,0x20,0x20,0x70, // "1"
+    0xF0,0x10,0xF0,0x80,0xF0, // "2"
+    0xF0,0x10,0xF0,0x10,0xF0, // "3"
+    0x90,0x90,0xF0,0x10,0x10, // "4"
+    0xF0,0x80,0xF0,0x10,0xF0, // "5"
+    0xF0,0x80,0xF0,0x90,0xF0, // "6"
+    0xF0,0x10,0x20,0x40,0x40, // "7"
+    0xF0,0x90,0xF0,0x90,0xF0, // "8"
+    0xF0,0x90,0xF0,0x10,0xF0, // "9"
+    0xF0,0x90,0xF0,0x90,0x90, // "A"
+    0xE0,0x90,0xE0,0x90,0xE0, // "B"
+    0xF0,0x80,0x80,0x80,0xF0, // "C"
+    0xE0,0x90,0x90,0x90,0xE0, // "D"
+    0xF0,0x80,0xF0,0x80,0xF0, // "E"
+    0xF0,0x80,0xF0,0x80,0x80, // "F"
+}
+
+// NewCPU returns an initialized CPU with the program loaded at 0x200
+func NewCPU(programData []byte) (*CPU, error) {
+    return NewCPUWithQuirks(programData, QuirksModern)
+}
+
+// NewCPUWithQuirks returns an initialized CPU with the quirks set before the
+// program is loaded, so programs larger than the classic address space load
+// only with XO-CHIP enabled
+func NewCPUWithQuirks(programData []byte, quirks Quirks) (*CPU, error) {
+    cpu := &CPU{Quirks: quirks}
+    cpu.Seed(time.Now().UnixNano())
+    cpu.Initialize()
+    if err := cpu.LoadProgram(programData); err != nil {
+        return nil, err
+    }
+    return cpu, nil
+}
+
+func (c *CPU) Initialize() {
+    c.DelayTimer = 0
+    c.SoundTimer = 0
+    c.ProgramCounter = ProgramStart
+    c.Index = 0
+    c.StackPointer = 0
+    c.DisplayBuffer = [HiResWidth][HiResHeight]byte{}
+    c.HighRes = false
+    c.Planes = 1
+    c.AudioPattern = [PatternSize]byte{}
+    c.Pitch = DefaultPitch
+    c.Memory = [MemorySize]byte{}
+    c.Stack = [16]uint16{}
+    c.Register = [16]byte {0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0}
+    c.Keypad = [KeyCount]bool{}
+    c.keyHeld = false
+    c.heldKey = 0
+    c.memoryVersion++
+    for i := 0;i< len(fontSet);i++ {
+        c.Memory[i] = fontSet[i]
+    }
+    copy(c.Memory[BigFontStart:], bigFontSet)
+}
+
+// Cycle executes a single instruction. If the instruction can not be
+// executed an *ExecutionError is returned and the CPU is left unchanged.
+func (c *CPU) Cycle() error {
+    c.pollInput()
+    if c.Tracer == nil {
+        return c.execute()
+    }
+    before := c.traceState()
+    err := c.execute()
+    after := c.traceState()
+    c.Tracer.Trace(&before, &after, err)
+    return err
+}
+
+// execute executes the instruction at the program counter
+func (c *CPU) execute() error {
+    if !c.inMemory(c.ProgramCounter, 2) {
+        return c.fault(ErrPCOutOfBounds, 0)
+    }
+    in := &instructions[c.word(c.ProgramCounter)]
+    if err := c.handlers()[in.Op](c, in); err != nil {
+        return err
+    }
+    c.ProgramCounter += 2
+    return nil
+}
+
+// shiftSource returns the register shifted by 8xy6 and 8xyE
+func (c *CPU) shiftSource(x, y uint16) byte {
+    if c.Quirks.ShiftVY {
+        return c.Register[y]
+    }
+    return c.Register[x]
+}
+
+// TickTimers counts down the delay and sound timers. It should be called at
+// TimerFrequency, regardless of the number of instructions executed.
+func (c *CPU) TickTimers() {
+    if c.SoundTimer > 0 { c.SoundTimer-- }
+    if c.DelayTimer > 0 { c.DelayTimer-- }
+}
+
+// LoadProgram copies the program into memory at ProgramStart. Programs
+// larger than ClassicMaxProgramSize are only loaded with XO-CHIP enabled.
+func (c *CPU) LoadProgram(data []byte) error {
+    if max := c.memorySize() - ProgramStart; len(data) > max {
+        return fmt.Errorf("%w: %d bytes, at most %d bytes fit in memory", ErrProgramTooLarge, len(data), max)
+    }
+    for i := 0; i<len(data);i++ {
+        c.Memory[i + ProgramStart] = data[i]
+    }
+    c.memoryVersion++
+    return nil
+}
@@ -0,0 +1,1286 @@
+package chip8
+
+import (
+    "errors"
+    "testing"
+)
+
+func NewTestCPU(ops ...uint16) *CPU {
+    c, err := NewCPU(Build(ops...))
+    if err != nil {
+        panic(err)
+    }
+    return c
+}
+
+func Test_CLS(t *testing.T) {
+    c := NewTestCPU(
+        CLS(),
+    )
+    c.DisplayBuffer[0][0] = 1
+
+    c.Cycle()
+
+    if c.DisplayBuffer[0][0] != 0 {
+        t.Error("display buffer was not cleared")
+    }
+}
+
+func Test_CALL(t *testing.T) {
+    c := NewTestCPU(
+        CALL(0x204),
+    )
+    c.Cycle()
+
+    if c.ProgramCounter != 0x204 {
+        t.Error("program counter is wrong")
+    }
+    if c.Stack[c.StackPointer - 1] != 0x200 {
+        t.Error("stack value is wrong")
+    }
+}
+
+func Test_RET(t *testing.T) {
+    c := NewTestCPU(
+        CALL(0x204),
+        NOP(),
+        RET(),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x202 {
+        t.Errorf("program counter is wrong: %v", c.ProgramCounter)
+    }
+}
+
+func Test_JP(t *testing.T) {
+    c := NewTestCPU(
+        JP(0x204),
+    )
+    c.Cycle()
+
+    if c.ProgramCounter != 0x204 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SE_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        SE(0x1, 0x12),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x206 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SE_not_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        SE(0x1, 0x13),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x204 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SNE_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        SNE(0x1, 0x12),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x204 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SNE_not_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        SNE(0x1, 0x13),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x206 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SE_VXY_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x2, 0x12),
+        SE_R(0x1, 0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x208 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_SE_VXY_not_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x1, 0x13),
+        SE_R(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x206 {
+        t.Error("program counter is wrong")
+    }
+}
+
+func Test_LD(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+    )
+    c.Cycle()
+
+    if c.Register[1] != 0x12 {
+        t.Error("value wasn't set")
+    }
+}
+
+func Test_ADD(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        ADD(0x1, 0x10),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[1] != 0x22 {
+        t.Error("value wasn't added")
+    }
+}
+
+func Test_ADD_overflow(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0xFF),
+        ADD(0x1, 0x10),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[1] != 0x0F {
+        t.Error("value wasn't added")
+    }
+
+    if c.Register[0xF] != 0 {
+        t.Error("carry should be ignored")
+    }
+}
+
+func Test_LD_R(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x20),
+        LD_R(0x2, 0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != c.Register[0x2] || c.Register[0x1] != 0x20 {
+        t.Error("registers not set properly")
+    }
+}
+
+func Test_OR(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0x20),
+        LD(0x2, 0x10),
+        OR(0x1, 0x2),
+        OR(0x1, 0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x30 {
+        t.Error("unexpected value")
+    }
+}
+
+func Test_AND(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0xFF),
+        LD(0x2, 0x04),
+        AND(0x1, 0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x04 {
+        t.Error("unexpected value")
+    }
+}
+
+func Test_XOR(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x10),
+        LD(0x2, 0xFF),
+        XOR(0x1, 0x2),
+    )
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0xEF {
+        t.Error("unexpected value")
+    }
+}
+
+func Test_ADD_R(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0x12),
+        LD(0x2, 0x15),
+        ADD_R(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x27 {
+        t.Error("unexpected value")
+    }
+
+    if c.Register[0xF] != 0x0 {
+        t.Error("unexpected carry")
+    }
+}
+
+func Test_ADD_R_overflow(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0xFF),
+        LD(0x2, 0x15),
+        ADD_R(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x14 {
+        t.Errorf("unexpected value: %v", c.Register[0x1])
+    }
+
+    if c.Register[0xF] != 0x1 {
+        t.Errorf("unexpected carry: %v", c.Register[0xF])
+    }
+}
+
+func Test_ADD_R_flag_register(t *testing.T) {
+    c := NewTestCPU(
+        LD(0xF, 0xFF),
+        LD(0x1, 0x02),
+        ADD_R(0xF, 0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0xF] != 0x1 {
+        t.Errorf("the carry should replace the result: %v", c.Register[0xF])
+    }
+}
+
+func Test_SUB(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0x12),
+        LD(0x2, 0x10),
+        SUB(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x2 {
+        t.Error("unexpected value")
+    }
+
+    if c.Register[0xF] != 0x1 {
+        t.Error("unexpected borrow value")
+    }
+}
+
+func Test_SUB_borrow(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1,0x10),
+        LD(0x2, 0x12),
+        SUB(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0xFE {
+        t.Error("unexpected value")
+    }
+
+    if c.Register[0xF] != 0x0 {
+        t.Error("unexpected borrow value")
+    }
+}
+
+func Test_SUB_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x2, 0x12),
+        SUB(0x1, 0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x0 {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x1 {
+        t.Errorf("equal values should not borrow: %v", c.Register[0xF])
+    }
+}
+
+func Test_SHR(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x4),
+        SHR(0x1),
+    )
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x2 {
+        t.Error("unexpected value")
+    }
+
+    if c.Register[0xF] != 0 {
+        t.Error("unexpected value")
+    }
+}
+
+func Test_SHR_carry(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x5),
+        SHR(0x1),
+    )
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x2 {
+        t.Error("unexpected value")
+    }
+
+    if c.Register[0xF] != 1 {
+        t.Error("unexpected value")
+    }
+}
+
+func Test_SUBN(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x10),
+        LD(0x2, 0x12),
+        SUBN(0x1,0x2),
+    )
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x2 {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x1 {
+        t.Error("unexpected borrow")
+    }
+}
+
+func Test_SUBN_borrow(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0xFF),
+        LD(0x2, 0x12),
+        SUBN(0x1,0x2),
+    )
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x13 {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x0 {
+        t.Error("unexpected borrow")
+    }
+}
+
+func Test_SUBN_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x2, 0x12),
+        SUBN(0x1, 0x2),
+    )
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x0 {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x1 {
+        t.Errorf("equal values should not borrow: %v", c.Register[0xF])
+    }
+}
+
+func Test_SHL(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x7E),
+        SHL(0x1),
+    )
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0xFC {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x0 {
+        t.Error("unexpected carry")
+    }
+}
+
+func Test_SHL_carry(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x80),
+        SHL(0x1),
+    )
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x1] != 0x00 {
+        t.Error("unexpected value")
+    }
+    if c.Register[0xF] != 0x1 {
+        t.Error("unexpected carry")
+    }
+}
+
+func Test_SNE_R_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x2, 0x12),
+        SNE_R(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x206 {
+        t.Error("unexpected pc")
+    }
+}
+
+func Test_SNE_R_not_equal(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x12),
+        LD(0x2, 0x13),
+        SNE_R(0x1,0x2),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x208 {
+        t.Error("unexpected pc")
+    }
+}
+
+func Test_LDI(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x120),
+    )
+
+    c.Cycle()
+
+    if c.Index != 0x120 {
+        t.Error("unexpected i")
+    }
+}
+
+func Test_JP_R(t *testing.T) {
+    c := NewTestCPU(
+        LD(0, 0x12),
+        JP_R(0x200),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x212 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+}
+
+type constantRandom byte
+
+func (r constantRandom) RandomByte() byte {
+    return byte(r)
+}
+
+func Test_RND(t *testing.T) {
+    c := NewTestCPU(RND(1, 0x13))
+    c.Random = constantRandom(0xFF)
+    c.Cycle()
+
+    if c.Register[0x1] != 0x13 {
+        t.Errorf("random value should be masked with kk: %x", c.Register[0x1])
+    }
+}
+
+func Test_RND_seed(t *testing.T) {
+    program := []uint16{
+        RND(0x0, 0xFF),
+        RND(0x1, 0xFF),
+        RND(0x2, 0xFF),
+        RND(0x3, 0xFF),
+    }
+    a := NewTestCPU(program...)
+    b := NewTestCPU(program...)
+    a.Seed(42)
+    b.Seed(42)
+    for i := 0; i < len(program); i++ {
+        a.Cycle()
+        b.Cycle()
+    }
+
+    if a.Register != b.Register {
+        t.Errorf("same seed should give the same values: %v, %v", a.Register, b.Register)
+    }
+    if a.Register[0] == a.Register[1] && a.Register[1] == a.Register[2] && a.Register[2] == a.Register[3] {
+        t.Errorf("values should not all be the same: %v", a.Register)
+    }
+}
+
+func Test_DRW(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x2),
+        LD(0x2, 0x3),
+        LDI(0x300),
+        DRW(0x1, 0x2, 1),
+    )
+    c.Memory[0x300] = 0b10010001
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    for b := 0; b < 8; b++ {
+        expected := byte(0)
+        if b == 0 || b == 3 || b == 7 {
+            expected = 1
+        }
+        if c.DisplayBuffer[2 + b][3] != expected {
+            t.Errorf("unexpected pixel at %d: %v", 2 + b, c.DisplayBuffer[2 + b][3])
+        }
+    }
+    if c.DisplayBuffer[1][3] != 0 || c.DisplayBuffer[10][3] != 0 {
+        t.Error("pixels outside the sprite should not be drawn")
+    }
+    if c.Register[0xF] != 0 {
+        t.Error("unexpected collision")
+    }
+}
+
+func Test_DRW_collision(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x300),
+        DRW(0x0, 0x0, 1),
+        LDI(0x301),
+        DRW(0x0, 0x0, 1),
+        DRW(0x0, 0x0, 1),
+    )
+    c.Memory[0x300] = 0b11000000
+    c.Memory[0x301] = 0b00110000
+
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0xF] != 0 {
+        t.Error("drawing next to lit pixels should not collide")
+    }
+
+    c.Cycle()
+
+    if c.Register[0xF] != 1 {
+        t.Error("erasing pixels should collide")
+    }
+    if c.DisplayBuffer[0][0] != 1 || c.DisplayBuffer[2][0] != 0 {
+        t.Error("unexpected display")
+    }
+}
+
+func Test_DRW_collision_VF(t *testing.T) {
+    c := NewTestCPU(
+        LD(0xF, 0x4),
+        DRW(0xF, 0xF, 1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0xF] != 0 || c.DisplayBuffer[4][4] != 1 {
+        t.Error("sprite should be drawn at the coordinates in VF")
+    }
+}
+
+func Test_DRW_clip(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 60),
+        LD(0x2, 31),
+        LDI(0x300),
+        DRW(0x1, 0x2, 2),
+    )
+    c.Memory[0x300] = 0xFF
+    c.Memory[0x301] = 0xFF
+
+    for i := 0; i < 4; i++ {
+        c.Cycle()
+    }
+
+    for x := 60; x < 64; x++ {
+        if c.DisplayBuffer[x][31] != 1 {
+            t.Errorf("pixel %d should be drawn", x)
+        }
+    }
+    for x := 0; x < 4; x++ {
+        if c.DisplayBuffer[x][31] != 0 || c.DisplayBuffer[x][0] != 0 {
+            t.Errorf("pixel %d should be clipped", x)
+        }
+    }
+    if c.DisplayBuffer[60][0] != 0 {
+        t.Error("row should be clipped")
+    }
+}
+
+func Test_DRW_clip_wraps_start(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 64 + 2),
+        LD(0x2, 32 + 1),
+        LDI(0x300),
+        DRW(0x1, 0x2, 1),
+    )
+    c.Memory[0x300] = 0x80
+
+    for i := 0; i < 4; i++ {
+        c.Cycle()
+    }
+
+    if c.DisplayBuffer[2][1] != 1 {
+        t.Error("start coordinate should wrap")
+    }
+}
+
+func Test_DRW_wrap(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 60),
+        LD(0x2, 31),
+        LDI(0x300),
+        DRW(0x1, 0x2, 2),
+    )
+    c.Quirks.DrawMode = Wrap
+    c.Memory[0x300] = 0xFF
+    c.Memory[0x301] = 0xFF
+
+    for i := 0; i < 4; i++ {
+        c.Cycle()
+    }
+
+    for _, y := range []int{31, 0} {
+        for x := 0; x < 64; x++ {
+            expected := byte(0)
+            if x < 4 || x >= 60 {
+                expected = 1
+            }
+            if c.DisplayBuffer[x][y] != expected {
+                t.Errorf("unexpected pixel at %d,%d: %v", x, y, c.DisplayBuffer[x][y])
+            }
+        }
+    }
+}
+
+func Test_SKP(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0xA),
+        SKP(0x1),
+        SKP(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x204 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+
+    c.Keypad[0xA] = true
+    c.Cycle()
+
+    if c.ProgramCounter != 0x208 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+}
+
+func Test_SKNP(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0xA),
+        SKNP(0x1),
+        NOP(),
+        SKNP(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x206 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+
+    c.Keypad[0xA] = true
+    c.Cycle()
+
+    if c.ProgramCounter != 0x208 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+}
+
+func Test_LD_R_DT(t *testing.T) {
+    c := NewTestCPU(
+        LD_VX_DT(0x1),
+    )
+    c.DelayTimer = 0x42
+    c.Cycle()
+
+    if c.Register[0x1] != 0x42 {
+        t.Errorf("unexpected value: %v", c.Register[0x1])
+    }
+}
+
+func Test_LDK(t *testing.T) {
+    c := NewTestCPU(
+        LD_VX_K(0x5),
+    )
+    c.Cycle()
+
+    if c.ProgramCounter != 0x200 {
+        t.Errorf("should wait for a key press, pc: %x", c.ProgramCounter)
+    }
+
+    c.Keypad[0x7] = true
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x200 {
+        t.Errorf("should wait for the key to be released, pc: %x", c.ProgramCounter)
+    }
+
+    c.Keypad[0x7] = false
+    c.Cycle()
+
+    if c.ProgramCounter != 0x202 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+    if c.Register[0x5] != 0x7 {
+        t.Errorf("unexpected key: %v", c.Register[0x5])
+    }
+}
+
+type testInput struct {
+    keys [KeyCount]bool
+}
+
+func (i *testInput) KeyState() [KeyCount]bool {
+    return i.keys
+}
+
+func Test_Input(t *testing.T) {
+    input := &testInput{}
+    c := NewTestCPU(
+        LD(0x1, 0x3),
+        SKP(0x1),
+    )
+    c.Input = input
+    input.keys[0x3] = true
+
+    c.Cycle()
+    c.Cycle()
+
+    if !c.Keypad[0x3] {
+        t.Error("keypad was not updated from input")
+    }
+    if c.ProgramCounter != 0x206 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+}
+
+func Test_LD_DT_R(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x20),
+        LD_DT_VX(0x1),
+        NOP(),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.DelayTimer != 0x20 {
+        t.Errorf("unexpected delay timer: %v", c.DelayTimer)
+    }
+
+    c.Cycle()
+
+    if c.DelayTimer != 0x20 {
+        t.Error("delay timer should not count down per instruction")
+    }
+}
+
+func Test_LD_ST_R(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x20),
+        LD_ST_VX(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.SoundTimer != 0x20 {
+        t.Errorf("unexpected sound timer: %v", c.SoundTimer)
+    }
+}
+
+func Test_TickTimers(t *testing.T) {
+    c := NewTestCPU()
+    c.DelayTimer = 2
+    c.SoundTimer = 1
+
+    c.TickTimers()
+
+    if c.DelayTimer != 1 || c.SoundTimer != 0 {
+        t.Errorf("unexpected timers: %v, %v", c.DelayTimer, c.SoundTimer)
+    }
+
+    c.TickTimers()
+
+    if c.DelayTimer != 0 || c.SoundTimer != 0 {
+        t.Errorf("timers should stop at zero: %v, %v", c.DelayTimer, c.SoundTimer)
+    }
+}
+
+func Test_ADDI(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD(0x1, 0x12),
+        ADD_I(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Index != 0x412 {
+        t.Errorf("unexpected i: %x", c.Index)
+    }
+}
+
+func Test_LDF(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0xA),
+        LDF(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Index != 0xA * 5 {
+        t.Errorf("unexpected i: %x", c.Index)
+    }
+}
+
+func Test_LDB(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD(0x1, 174),
+        LDB(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Memory[0x400] != 1 {
+        t.Errorf("unexpected value for hundreds: %v", c.Memory[0x400])
+    }
+    if c.Memory[0x401] != 7 {
+        t.Errorf("unexpected value for tens: %v", c.Memory[0x401])
+    }
+    if c.Memory[0x402] != 4 {
+        t.Errorf("unexpected value for ones: %v", c.Memory[0x402])
+    }
+}
+
+func Test_LD_I_R(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD(0x0, 4),
+        LD(0x1, 3),
+        LD(0x2, 10),
+        LD(0x3, 123),
+        LD_I_VX(0x2),
+    )
+    for i := 0; i < 6; i++ {
+        c.Cycle()
+    }
+
+    if c.Memory[0x400] != 4 || c.Memory[0x401] != 3 || c.Memory[0x402] != 10 {
+        t.Errorf("unexpected memory: %v", c.Memory[0x400:0x403])
+    }
+    if c.Memory[0x403] != 0 {
+        t.Errorf("v[3] should not be stored: %v", c.Memory[0x403])
+    }
+}
+
+func Test_LD_R_I(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD_VX_I(0x3),
+    )
+    c.Memory[0x400] = 4
+    c.Memory[0x401] = 3
+    c.Memory[0x402] = 10
+    c.Memory[0x403] = 100
+    c.Memory[0x404] = 123
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0x0] != 4 {
+        t.Errorf("unexpected v[0] value: %v", c.Register[0x0])
+    }
+    if c.Register[0x1] != 3 {
+        t.Errorf("unexpected v[1] value: %v", c.Register[0x1])
+    }
+    if c.Register[0x2] != 10 {
+        t.Errorf("unexpected v[2] value%v", c.Register[0x2])
+    }
+    if c.Register[0x3] != 100 {
+        t.Errorf("unexpected v[3] value: %v", c.Register[0x3])
+    }
+    if c.Register[0x4] != 0 {
+        t.Errorf("unexpected v[4] value: %v", c.Register[0x4])
+    }
+}
+
+func Test_LoadProgram_too_large(t *testing.T) {
+    c := &CPU{Quirks: QuirksXOChip}
+    c.Initialize()
+
+    if err := c.LoadProgram(make([]byte, MaxProgramSize)); err != nil {
+        t.Errorf("program of maximum size should fit: %v", err)
+    }
+    if err := c.LoadProgram(make([]byte, MaxProgramSize + 1)); !errors.Is(err, ErrProgramTooLarge) {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
+
+func Test_NewCPUWithQuirks_program_size(t *testing.T) {
+    program := make([]byte, ClassicMaxProgramSize + 1)
+
+    if _, err := NewCPUWithQuirks(program, QuirksModern); !errors.Is(err, ErrProgramTooLarge) {
+        t.Errorf("program larger than the classic memory should be rejected: %v", err)
+    }
+    if _, err := NewCPUWithQuirks(program[:ClassicMaxProgramSize], QuirksModern); err != nil {
+        t.Errorf("program of the classic maximum size should fit: %v", err)
+    }
+    c, err := NewCPUWithQuirks(program, QuirksXOChip)
+    if err != nil {
+        t.Fatalf("program should fit with XO-CHIP: %v", err)
+    }
+    if !c.Quirks.XOChip {
+        t.Error("quirks were not set")
+    }
+}
+
+func assertExecutionError(t *testing.T, c *CPU, expected error, pc uint16) {
+    t.Helper()
+    err := c.Cycle()
+    var execErr *ExecutionError
+    if !errors.As(err, &execErr) {
+        t.Fatalf("expected an execution error, got %v", err)
+    }
+    if !errors.Is(err, expected) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if execErr.ProgramCounter != pc {
+        t.Errorf("unexpected pc in error: %x", execErr.ProgramCounter)
+    }
+    if c.ProgramCounter != pc {
+        t.Errorf("pc should not advance: %x", c.ProgramCounter)
+    }
+}
+
+func Test_Cycle_unknown_opcode(t *testing.T) {
+    c := NewTestCPU(
+        0x8008,
+        0xE0FF,
+        0xF0FF,
+    )
+    assertExecutionError(t, c, ErrUnknownOpcode, 0x200)
+
+    c.ProgramCounter = 0x202
+    assertExecutionError(t, c, ErrUnknownOpcode, 0x202)
+
+    c.ProgramCounter = 0x204
+    assertExecutionError(t, c, ErrUnknownOpcode, 0x204)
+}
+
+func Test_Cycle_stack_overflow(t *testing.T) {
+    c := NewTestCPU(
+        CALL(0x200),
+    )
+    for i := 0; i < 16; i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+    }
+    assertExecutionError(t, c, ErrStackOverflow, 0x200)
+}
+
+func Test_Cycle_stack_underflow(t *testing.T) {
+    c := NewTestCPU(
+        RET(),
+    )
+    assertExecutionError(t, c, ErrStackUnderflow, 0x200)
+}
+
+func Test_Cycle_memory_out_of_bounds(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0xFFE),
+        LD_I_VX(0x2),
+        LD_VX_I(0x2),
+        LDB(0x0),
+        DRW(0x0, 0x0, 3),
+    )
+    c.Cycle()
+    assertExecutionError(t, c, ErrMemoryOutOfBounds, 0x202)
+
+    c.ProgramCounter = 0x204
+    assertExecutionError(t, c, ErrMemoryOutOfBounds, 0x204)
+
+    c.ProgramCounter = 0x206
+    assertExecutionError(t, c, ErrMemoryOutOfBounds, 0x206)
+
+    c.ProgramCounter = 0x208
+    assertExecutionError(t, c, ErrMemoryOutOfBounds, 0x208)
+}
+
+func Test_Cycle_pc_out_of_bounds(t *testing.T) {
+    c := NewTestCPU()
+    c.ProgramCounter = 0xFFF
+    assertExecutionError(t, c, ErrPCOutOfBounds, 0xFFF)
+}
+
+func Test_Quirks_ShiftVY(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x1, 0x10),
+        LD(0x2, 0x81),
+        SHR(0x1) | 0x2 << 4,
+        SHL(0x3) | 0x2 << 4,
+    )
+    c.Quirks.ShiftVY = true
+    for i := 0; i < 3; i++ {
+        c.Cycle()
+    }
+
+    if c.Register[0x1] != 0x40 || c.Register[0xF] != 1 {
+        t.Errorf("8xy6 should shift vy: %x, vf %v", c.Register[0x1], c.Register[0xF])
+    }
+
+    c.Cycle()
+
+    if c.Register[0x3] != 0x02 || c.Register[0xF] != 1 {
+        t.Errorf("8xyE should shift vy: %x, vf %v", c.Register[0x3], c.Register[0xF])
+    }
+    if c.Register[0x2] != 0x81 {
+        t.Error("vy should not change")
+    }
+}
+
+func Test_SHL_VF(t *testing.T) {
+    c := NewTestCPU(
+        LD(0xF, 0x81),
+        SHL(0xF),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    if c.Register[0xF] != 1 {
+        t.Errorf("flag should be written after the result: %v", c.Register[0xF])
+    }
+}
+
+func Test_Quirks_IncrementIndex(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD_I_VX(0x2),
+        LD_VX_I(0x1),
+    )
+    c.Quirks.IncrementIndex = true
+    c.Cycle()
+    c.Cycle()
+
+    if c.Index != 0x403 {
+        t.Errorf("Fx55 should increment i: %x", c.Index)
+    }
+
+    c.Cycle()
+
+    if c.Index != 0x405 {
+        t.Errorf("Fx65 should increment i: %x", c.Index)
+    }
+}
+
+func Test_Quirks_IncrementIndex_disabled(t *testing.T) {
+    c := NewTestCPU(
+        LDI(0x400),
+        LD_I_VX(0x2),
+        LD_VX_I(0x1),
+    )
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.Index != 0x400 {
+        t.Errorf("i should not change: %x", c.Index)
+    }
+}
+
+func Test_Quirks_JumpVX(t *testing.T) {
+    c := NewTestCPU(
+        LD(0x0, 0x10),
+        LD(0x3, 0x04),
+        JP_R(0x300),
+    )
+    c.Quirks.JumpVX = true
+    c.Cycle()
+    c.Cycle()
+    c.Cycle()
+
+    if c.ProgramCounter != 0x304 {
+        t.Errorf("unexpected pc: %x", c.ProgramCounter)
+    }
+}
+
+func Test_Quirks_ResetVF(t *testing.T) {
+    for _, op := range []uint16{OR(0x1, 0x2), AND(0x1, 0x2), XOR(0x1, 0x2)} {
+        c := NewTestCPU(
+            LD(0xF, 0x5),
+            op,
+        )
+        c.Quirks.ResetVF = true
+        c.Cycle()
+        c.Cycle()
+
+        if c.Register[0xF] != 0 {
+            t.Errorf("%04X should reset vf: %v", op, c.Register[0xF])
+        }
+
+        c = NewTestCPU(
+            LD(0xF, 0x5),
+            op,
+        )
+        c.Cycle()
+        c.Cycle()
+
+        if c.Register[0xF] != 0x5 {
+            t.Errorf("%04X should not change vf: %v", op, c.Register[0xF])
+        }
+    }
+}
+
+func Test_QuirksProfile(t *testing.T) {
+    for _, name := range QuirksProfiles() {
+        if _, ok := QuirksProfile(name); !ok {
+            t.Errorf("profile %q not found", name)
+        }
+    }
+    if q, _ := QuirksProfile("vip"); q != QuirksCOSMACVIP {
+        t.Error("unexpected quirks for vip")
+    }
+    if _, ok := QuirksProfile("unknown"); ok {
+        t.Error("unknown profile should not be found")
+    }
+}
+
+// benchmarkProgram runs a program in a loop, b.N instructions in total
+func benchmarkProgram(b *testing.B, ops ...uint16) {
+    c := NewTestCPU(ops...)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if err := c.Cycle(); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func Benchmark_Cycle_arithmetic(b *testing.B) {
+    benchmarkProgram(b,
+        LD(0x0, 0x12),
+        ADD(0x1, 0x03),
+        LD_R(0x2, 0x1),
+        ADD_R(0x2, 0x0),
+        SUB(0x3, 0x2),
+        XOR(0x3, 0x1),
+        SHR(0x3),
+        SE(0x4, 0xFF),
+        JP(0x200),
+    )
+}
+
+func Benchmark_Cycle_mixed(b *testing.B) {
+    benchmarkProgram(b,
+        LDI(0x300),
+        LD(0x0, 0x05),
+        DRW(0x0, 0x1, 0x5),
+        ADD_I(0x0),
+        LDB(0x0),
+        LD_VX_I(0x2),
+        SKP(0x0),
+        SNE_R(0x0, 0x1),
+        CALL(0x214),
+        JP(0x200),
+        RET(),
+    )
+}
+
+func Benchmark_Decode(b *testing.B) {
+    var in Instruction
+    for i := 0; i < b.N; i++ {
+        in = Decode(uint16(i))
+    }
+    if in.OpCode != uint16(b.N - 1) {
+        b.Fatal("unexpected instruction")
+    }
+}
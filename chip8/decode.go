@@ -0,0 +1,308 @@
+package chip8
+
+// Op identifies an instruction, independent of its operands. The names
+// follow the opcode builders, with SYS for the machine code calls that are
+// ignored and LD_I_LONG for the XO-CHIP F000 nnnn.
+type Op byte
+
+const (
+    OpInvalid Op = iota
+    OpSYS
+    OpCLS
+    OpRET
+    OpSCD
+    OpSCU
+    OpSCR
+    OpSCL
+    OpEXIT
+    OpLOW
+    OpHIGH
+    OpJP
+    OpCALL
+    OpSE
+    OpSNE
+    OpSE_R
+    OpSAVE
+    OpLOAD
+    OpLD
+    OpADD
+    OpLD_R
+    OpOR
+    OpAND
+    OpXOR
+    OpADD_R
+    OpSUB
+    OpSHR
+    OpSUBN
+    OpSHL
+    OpSNE_R
+    OpLDI
+    OpJP_R
+    OpRND
+    OpDRW
+    OpSKP
+    OpSKNP
+    OpLD_I_LONG
+    OpPLANE
+    OpAUDIO
+    OpLD_VX_DT
+    OpLD_VX_K
+    OpLD_DT_VX
+    OpLD_ST_VX
+    OpADD_I
+    OpLDF
+    OpLD_HF_VX
+    OpLDB
+    OpLD_PITCH_VX
+    OpLD_I_VX
+    OpLD_VX_I
+    OpLD_R_VX
+    OpLD_VX_R
+    // opCount is the number of Ops, for tables indexed by Op
+    opCount
+)
+
+var opNames = [opCount]string{
+    OpInvalid:     "INVALID",
+    OpSYS:         "SYS",
+    OpCLS:         "CLS",
+    OpRET:         "RET",
+    OpSCD:         "SCD",
+    OpSCU:         "SCU",
+    OpSCR:         "SCR",
+    OpSCL:         "SCL",
+    OpEXIT:        "EXIT",
+    OpLOW:         "LOW",
+    OpHIGH:        "HIGH",
+    OpJP:          "JP",
+    OpCALL:        "CALL",
+    OpSE:          "SE",
+    OpSNE:         "SNE",
+    OpSE_R:        "SE_R",
+    OpSAVE:        "SAVE",
+    OpLOAD:        "LOAD",
+    OpLD:          "LD",
+    OpADD:         "ADD",
+    OpLD_R:        "LD_R",
+    OpOR:          "OR",
+    OpAND:         "AND",
+    OpXOR:         "XOR",
+    OpADD_R:       "ADD_R",
+    OpSUB:         "SUB",
+    OpSHR:         "SHR",
+    OpSUBN:        "SUBN",
+    OpSHL:         "SHL",
+    OpSNE_R:       "SNE_R",
+    OpLDI:         "LDI",
+    OpJP_R:        "JP_R",
+    OpRND:         "RND",
+    OpDRW:         "DRW",
+    OpSKP:         "SKP",
+    OpSKNP:        "SKNP",
+    OpLD_I_LONG:   "LD_I_LONG",
+    OpPLANE:       "PLANE",
+    OpAUDIO:       "AUDIO",
+    OpLD_VX_DT:    "LD_VX_DT",
+    OpLD_VX_K:     "LD_VX_K",
+    OpLD_DT_VX:    "LD_DT_VX",
+    OpLD_ST_VX:    "LD_ST_VX",
+    OpADD_I:       "ADD_I",
+    OpLDF:         "LDF",
+    OpLD_HF_VX:    "LD_HF_VX",
+    OpLDB:         "LDB",
+    OpLD_PITCH_VX: "LD_PITCH_VX",
+    OpLD_I_VX:     "LD_I_VX",
+    OpLD_VX_I:     "LD_VX_I",
+    OpLD_R_VX:     "LD_R_VX",
+    OpLD_VX_R:     "LD_VX_R",
+}
+
+func (op Op) String() string {
+    if op >= opCount {
+        return opNames[OpInvalid]
+    }
+    return opNames[op]
+}
+
+// XOChip returns true for the instructions that only exist in XO-CHIP. 00Dn
+// is not one of them, as it is a SYS call on the other platforms.
+func (op Op) XOChip() bool {
+    switch op {
+    case OpSAVE, OpLOAD, OpLD_I_LONG, OpPLANE, OpAUDIO, OpLD_PITCH_VX:
+        return true
+    }
+    return false
+}
+
+// SuperChip returns true for the instructions added by SUPER-CHIP. Without it
+// the 00Cn and 00FB to 00FF instructions are SYS calls.
+func (op Op) SuperChip() bool {
+    switch op {
+    case OpSCD, OpSCR, OpSCL, OpEXIT, OpLOW, OpHIGH, OpLD_HF_VX, OpLD_R_VX, OpLD_VX_R:
+        return true
+    }
+    return false
+}
+
+// Instruction is a decoded opcode
+type Instruction struct {
+    Op     Op
+    OpCode uint16
+    // X and Y are the registers in the second and third nibble
+    X      byte
+    Y      byte
+    // N, NN and NNN are the lowest 4, 8 and 12 bits
+    N      byte
+    NN     byte
+    NNN    uint16
+}
+
+// Size returns the size of the instruction in bytes, which is 4 for the
+// XO-CHIP F000 nnnn and 2 for all others
+func (in Instruction) Size() int {
+    if in.Op == OpLD_I_LONG {
+        return 4
+    }
+    return 2
+}
+
+// instructions holds every opcode decoded, so decoding is a single lookup
+var instructions = func() *[0x10000]Instruction {
+    var table [0x10000]Instruction
+    for i := range table {
+        opCode := uint16(i)
+        table[i] = Instruction{
+            Op:     decodeOp(opCode),
+            OpCode: opCode,
+            X:      byte(opCode >> 8) & 0xF,
+            Y:      byte(opCode >> 4) & 0xF,
+            N:      byte(opCode) & 0xF,
+            NN:     byte(opCode),
+            NNN:    opCode & 0x0FFF,
+        }
+    }
+    return &table
+}()
+
+// Decode decodes an opcode, including the instructions of all extensions
+func Decode(opCode uint16) Instruction {
+    return instructions[opCode]
+}
+
+// fOps maps the lowest byte of the Fxkk instructions onto their Op
+var fOps = map[byte]Op{
+    0x07: OpLD_VX_DT,
+    0x0A: OpLD_VX_K,
+    0x15: OpLD_DT_VX,
+    0x18: OpLD_ST_VX,
+    0x1E: OpADD_I,
+    0x29: OpLDF,
+    0x30: OpLD_HF_VX,
+    0x33: OpLDB,
+    0x3A: OpLD_PITCH_VX,
+    0x55: OpLD_I_VX,
+    0x65: OpLD_VX_I,
+    0x75: OpLD_R_VX,
+    0x85: OpLD_VX_R,
+}
+
+// aluOps maps the lowest nibble of the 8xyn instructions onto their Op
+var aluOps = map[uint16]Op{
+    0x0: OpLD_R,
+    0x1: OpOR,
+    0x2: OpAND,
+    0x3: OpXOR,
+    0x4: OpADD_R,
+    0x5: OpSUB,
+    0x6: OpSHR,
+    0x7: OpSUBN,
+    0xE: OpSHL,
+}
+
+// decodeOp returns the Op of an opcode, which is only used to fill the
+// instructions table
+func decodeOp(opCode uint16) Op {
+    n := opCode & 0x000F
+    kk := byte(opCode)
+    switch opCode & 0xF000 {
+    case 0x0000:
+        switch {
+        case opCode == 0x00E0:
+            return OpCLS
+        case opCode == 0x00EE:
+            return OpRET
+        case opCode & 0xFFF0 == 0x00C0:
+            return OpSCD
+        case opCode & 0xFFF0 == 0x00D0:
+            return OpSCU
+        case opCode == 0x00FB:
+            return OpSCR
+        case opCode == 0x00FC:
+            return OpSCL
+        case opCode == 0x00FD:
+            return OpEXIT
+        case opCode == 0x00FE:
+            return OpLOW
+        case opCode == 0x00FF:
+            return OpHIGH
+        }
+        return OpSYS
+    case 0x1000:
+        return OpJP
+    case 0x2000:
+        return OpCALL
+    case 0x3000:
+        return OpSE
+    case 0x4000:
+        return OpSNE
+    case 0x5000:
+        switch n {
+        case 0x0:
+            return OpSE_R
+        case 0x2:
+            return OpSAVE
+        case 0x3:
+            return OpLOAD
+        }
+    case 0x6000:
+        return OpLD
+    case 0x7000:
+        return OpADD
+    case 0x8000:
+        if op, ok := aluOps[n]; ok {
+            return op
+        }
+    case 0x9000:
+        if n == 0 {
+            return OpSNE_R
+        }
+    case 0xA000:
+        return OpLDI
+    case 0xB000:
+        return OpJP_R
+    case 0xC000:
+        return OpRND
+    case 0xD000:
+        return OpDRW
+    case 0xE000:
+        switch kk {
+        case 0x9E:
+            return OpSKP
+        case 0xA1:
+            return OpSKNP
+        }
+    case 0xF000:
+        switch {
+        case opCode == 0xF000:
+            return OpLD_I_LONG
+        case opCode == 0xF002:
+            return OpAUDIO
+        case kk == 0x01:
+            return OpPLANE
+        }
+        if op, ok := fOps[kk]; ok {
+            return op
+        }
+    }
+    return OpInvalid
+}
@@ -0,0 +1,87 @@
+package chip8
+
+import (
+    "errors"
+    "testing"
+)
+
+func Test_Decode(t *testing.T) {
+    tests := []struct {
+        opCode uint16
+        op     Op
+    }{
+        {0x00E0, OpCLS},
+        {0x0123, OpSYS},
+        {0x00D4, OpSCU},
+        {0x1234, OpJP},
+        {0x5122, OpSAVE},
+        {0x5121, OpInvalid},
+        {0x812E, OpSHL},
+        {0x8128, OpInvalid},
+        {0x9120, OpSNE_R},
+        {0x9121, OpInvalid},
+        {0xD120, OpDRW},
+        {0xE19E, OpSKP},
+        {0xE19F, OpInvalid},
+        {0xF000, OpLD_I_LONG},
+        {0xF100, OpInvalid},
+        {0xF201, OpPLANE},
+        {0xF002, OpAUDIO},
+        {0xF102, OpInvalid},
+        {0xF33A, OpLD_PITCH_VX},
+        {0xF365, OpLD_VX_I},
+    }
+    for _, test := range tests {
+        if in := Decode(test.opCode); in.Op != test.op {
+            t.Errorf("%04X: expected %v, got %v", test.opCode, test.op, in.Op)
+        }
+    }
+
+    in := Decode(0xD12F)
+    if in.OpCode != 0xD12F || in.X != 0x1 || in.Y != 0x2 || in.N != 0xF || in.NN != 0x2F || in.NNN != 0x12F {
+        t.Errorf("unexpected operands: %+v", in)
+    }
+    if Decode(0xF000).Size() != 4 || in.Size() != 2 {
+        t.Error("unexpected instruction size")
+    }
+    if OpLD_I_LONG.String() != "LD_I_LONG" || Op(200).String() != "INVALID" {
+        t.Errorf("unexpected names: %v, %v", OpLD_I_LONG, Op(200))
+    }
+}
+
+func Test_Decode_tables(t *testing.T) {
+    for op := OpInvalid; op < opCount; op++ {
+        if handlers[op] == nil || classicHandlers[op] == nil {
+            t.Errorf("%v: missing handler", op)
+        }
+        if opNames[op] == "" {
+            t.Errorf("%d: missing name", op)
+        }
+        if op != OpInvalid && mnemonics[op] == nil {
+            t.Errorf("%v: missing mnemonic", op)
+        }
+    }
+    // every opcode with a mnemonic is executed
+    for opCode := 0; opCode <= 0xFFFF; opCode++ {
+        _, valid := Mnemonic(uint16(opCode), nil)
+        if valid != (Decode(uint16(opCode)).Op != OpInvalid) {
+            t.Errorf("%04X: mnemonic and decoder disagree", opCode)
+        }
+    }
+}
+
+func Test_Decode_classic(t *testing.T) {
+    // the XO-CHIP instructions do not exist on other platforms, and 00Dn is
+    // an ignored machine code call
+    for _, op := range []uint16{SAVE(1, 2), LOAD(1, 2), 0xF000, PLANE(1), AUDIO(), LD_PITCH_VX(1)} {
+        c := NewTestCPU(op)
+        if err := c.Cycle(); !errors.Is(err, ErrUnknownOpcode) {
+            t.Errorf("%04X: expected ErrUnknownOpcode, got %v", op, err)
+        }
+    }
+    c := NewTestCPU(SCU(1))
+    c.DisplayBuffer[0][1] = 1
+    if err := c.Cycle(); err != nil || c.DisplayBuffer[0][1] != 1 || c.ProgramCounter != 0x202 {
+        t.Errorf("00D1 should be ignored, got %v", err)
+    }
+}
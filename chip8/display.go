@@ -0,0 +1,79 @@
+package chip8
+
+const (
+    // DisplayWidth is the width of the display in pixels, in low resolution
+    DisplayWidth = 64
+    // DisplayHeight is the height of the display in pixels, in low resolution
+    DisplayHeight = 32
+)
+
+// DrawMode selects how Dxyn draws sprites that cross the edge of the display
+type DrawMode int
+
+const (
+    // Clip wraps the start coordinate of the sprite around the display, and
+    // clips the pixels that fall past the right or bottom edge
+    Clip DrawMode = iota
+    // Wrap wraps every pixel of the sprite around the display, as some of
+    // the original interpreters did
+    Wrap
+)
+
+// Resolution returns the size of the display in the current mode. Only this
+// part of the DisplayBuffer, starting at the top left, is used.
+func (c *CPU) Resolution() (width, height int) {
+    if c.HighRes {
+        return HiResWidth, HiResHeight
+    }
+    return DisplayWidth, DisplayHeight
+}
+
+// drawSprite XORs the sprite at I onto the selected planes of the display at
+// (x, y). Sprites are 8 pixels wide, or 16 pixels wide with 2 bytes per row
+// for the SUPER-CHIP 16x16 sprites. With more than one plane selected the
+// sprite of every plane follows the previous one in memory. It returns true
+// if a lit pixel was erased.
+func (c *CPU) drawSprite(x, y byte, width, rows int) bool {
+    displayWidth, displayHeight := c.Resolution()
+    x0 := int(x) % displayWidth
+    y0 := int(y) % displayHeight
+    bytesPerRow := width / 8
+    collision := false
+    addr := int(c.Index)
+    for plane := byte(1); plane < 1 << PlaneCount; plane <<= 1 {
+        if c.planes() & plane == 0 {
+            continue
+        }
+        for row := 0; row < rows; row++ {
+            py := y0 + row
+            if py >= displayHeight {
+                if c.Quirks.DrawMode == Clip {
+                    break
+                }
+                py %= displayHeight
+            }
+            line := 0
+            for i := 0; i < bytesPerRow; i++ {
+                line = line << 8 | int(c.Memory[addr + row * bytesPerRow + i])
+            }
+            for b := 0; b < width; b++ {
+                if line & (1 << (width - 1 - b)) == 0 {
+                    continue
+                }
+                px := x0 + b
+                if px >= displayWidth {
+                    if c.Quirks.DrawMode == Clip {
+                        break
+                    }
+                    px %= displayWidth
+                }
+                if c.DisplayBuffer[px][py] & plane != 0 {
+                    collision = true
+                }
+                c.DisplayBuffer[px][py] ^= plane
+            }
+        }
+        addr += rows * bytesPerRow
+    }
+    return collision
+}
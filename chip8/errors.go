@@ -0,0 +1,64 @@
+package chip8
+
+import (
+    "errors"
+    "fmt"
+)
+
+var (
+    // ErrUnknownOpcode is returned for instructions the CPU does not implement
+    ErrUnknownOpcode = errors.New("unknown opcode")
+    // ErrStackOverflow is returned when CALL is executed with a full stack
+    ErrStackOverflow = errors.New("stack overflow")
+    // ErrStackUnderflow is returned when RET is executed with an empty stack
+    ErrStackUnderflow = errors.New("stack underflow")
+    // ErrMemoryOutOfBounds is returned when an instruction accesses memory
+    // past the end of the address space
+    ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+    // ErrPCOutOfBounds is returned when the program counter points past the
+    // end of the address space
+    ErrPCOutOfBounds = errors.New("program counter out of bounds")
+    // ErrExit is returned when the program exits with the SUPER-CHIP 00FD
+    // instruction
+    ErrExit = errors.New("program exited")
+)
+
+// ExecutionError describes an instruction that could not be executed. The
+// CPU state is left as it was before the instruction, so the error can be
+// inspected and execution can not continue past it by accident.
+type ExecutionError struct {
+    Err            error
+    ProgramCounter uint16
+    OpCode         uint16
+}
+
+func (e *ExecutionError) Error() string {
+    return fmt.Sprintf("%v at %03X (opcode %04X)", e.Err, e.ProgramCounter, e.OpCode)
+}
+
+func (e *ExecutionError) Unwrap() error {
+    return e.Err
+}
+
+// fault returns an ExecutionError for the instruction at the program counter
+func (c *CPU) fault(err error, opCode uint16) error {
+    return &ExecutionError{
+        Err:            err,
+        ProgramCounter: c.ProgramCounter,
+        OpCode:         opCode,
+    }
+}
+
+// inMemory returns true if n bytes starting at addr are within the address
+// space, which is only larger than ClassicMemorySize with XO-CHIP enabled
+func (c *CPU) inMemory(addr uint16, n int) bool {
+    return int(addr) + n <= c.memorySize()
+}
+
+// memorySize returns the size of the address space
+func (c *CPU) memorySize() int {
+    if c.Quirks.XOChip {
+        return MemorySize
+    }
+    return ClassicMemorySize
+}
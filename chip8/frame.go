@@ -0,0 +1,71 @@
+package chip8
+
+import (
+    "image/color"
+)
+
+// Frame is a copy of the visible part of the display, as published to a
+// Display once per frame
+type Frame struct {
+    Width  int
+    Height int
+    // Pixels holds the rows of the frame from top to bottom. Every pixel is a
+    // bitmask of the planes it is lit on, so 0 is unlit.
+    Pixels []byte
+}
+
+// At returns the pixel at x, y, counted from the top left
+func (f *Frame) At(x, y int) byte {
+    return f.Pixels[y * f.Width + x]
+}
+
+// Equal returns true if both frames have the same size and pixels
+func (f *Frame) Equal(other *Frame) bool {
+    if other == nil || f.Width != other.Width || f.Height != other.Height {
+        return false
+    }
+    for i, p := range f.Pixels {
+        if other.Pixels[i] != p {
+            return false
+        }
+    }
+    return true
+}
+
+// Palette holds the colors of the pixels: unlit, lit on the first plane, on
+// the second plane and on both planes. Only XO-CHIP programs use the last
+// two.
+type Palette [1 << PlaneCount]color.Color
+
+// DefaultPalette draws white on black, with shades of gray for the second
+// XO-CHIP plane
+var DefaultPalette = Palette{
+    color.Black,
+    color.White,
+    color.RGBA{0xAA, 0xAA, 0xAA, 0xFF},
+    color.RGBA{0x55, 0x55, 0x55, 0xFF},
+}
+
+// Display shows the frames of an emulation, such as in a window or a
+// terminal
+type Display interface {
+    // Show is called by the front end with the display at the end of every
+    // frame, even if the emulation is paused
+    Show(f *Frame) error
+}
+
+// Frame returns a copy of the display at its current resolution
+func (c *CPU) Frame() *Frame {
+    width, height := c.Resolution()
+    f := &Frame{
+        Width:  width,
+        Height: height,
+        Pixels: make([]byte, width * height),
+    }
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            f.Pixels[y * width + x] = c.DisplayBuffer[x][y]
+        }
+    }
+    return f
+}
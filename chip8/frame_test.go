@@ -0,0 +1,37 @@
+package chip8
+
+import (
+    "testing"
+)
+
+func Test_Frame(t *testing.T) {
+    c := NewTestCPU()
+    c.DisplayBuffer[63][0] = 1
+    c.DisplayBuffer[0][31] = 3
+    c.DisplayBuffer[64][0] = 1
+
+    f := c.Frame()
+    if f.Width != 64 || f.Height != 32 || len(f.Pixels) != 64 * 32 {
+        t.Fatalf("unexpected frame size: %dx%d with %d pixels", f.Width, f.Height, len(f.Pixels))
+    }
+    if f.At(63, 0) != 1 || f.At(0, 31) != 3 || f.At(0, 0) != 0 {
+        t.Errorf("unexpected pixels: %d %d %d", f.At(63, 0), f.At(0, 31), f.At(0, 0))
+    }
+
+    c.DisplayBuffer[63][0] = 0
+    if f.At(63, 0) != 1 {
+        t.Error("frame should be a copy of the display")
+    }
+    if f.Equal(c.Frame()) {
+        t.Error("frames should differ after the display changed")
+    }
+    c.DisplayBuffer[63][0] = 1
+    if !f.Equal(c.Frame()) {
+        t.Error("frames should be equal")
+    }
+
+    c.HighRes = true
+    if f = c.Frame(); f.Width != 128 || f.Height != 64 || f.At(64, 0) != 1 {
+        t.Errorf("unexpected high resolution frame: %dx%d", f.Width, f.Height)
+    }
+}
@@ -0,0 +1,276 @@
+package chip8
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// fuzzSteps is the number of instructions a fuzzed program runs for, with
+// the timers ticking every fuzzTickSteps instructions
+const (
+    fuzzSteps     = 500
+    fuzzTickSteps = 10
+)
+
+// fuzzOps build the instructions covered by the reference model from the
+// operands x, y and kk, and addr, an address inside the program
+var fuzzOps = []func(x, y, kk, addr uint16) uint16{
+    func(x, y, kk, addr uint16) uint16 { return CLS() },
+    func(x, y, kk, addr uint16) uint16 { return RET() },
+    func(x, y, kk, addr uint16) uint16 { return JP(addr) },
+    func(x, y, kk, addr uint16) uint16 { return CALL(addr) },
+    func(x, y, kk, addr uint16) uint16 { return SE(x, kk) },
+    func(x, y, kk, addr uint16) uint16 { return SNE(x, kk) },
+    func(x, y, kk, addr uint16) uint16 { return SE_R(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return LD(x, kk) },
+    func(x, y, kk, addr uint16) uint16 { return ADD(x, kk) },
+    func(x, y, kk, addr uint16) uint16 { return LD_R(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return OR(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return AND(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return XOR(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return ADD_R(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return SUB(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return SHR(x) | y << 4 },
+    func(x, y, kk, addr uint16) uint16 { return SUBN(x, y) },
+    func(x, y, kk, addr uint16) uint16 { return SHL(x) | y << 4 },
+    func(x, y, kk, addr uint16) uint16 { return SNE_R(x, y) },
+    // anywhere in memory, including the font and the program
+    func(x, y, kk, addr uint16) uint16 { return LDI(x << 8 | kk) },
+    func(x, y, kk, addr uint16) uint16 { return LDI(addr) },
+    func(x, y, kk, addr uint16) uint16 { return JP_R(addr) },
+    func(x, y, kk, addr uint16) uint16 { return RND(x, kk) },
+    func(x, y, kk, addr uint16) uint16 { return DRW(x, y, kk % 15 + 1) },
+    func(x, y, kk, addr uint16) uint16 { return SKP(x) },
+    func(x, y, kk, addr uint16) uint16 { return SKNP(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_VX_DT(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_VX_K(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_DT_VX(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_ST_VX(x) },
+    func(x, y, kk, addr uint16) uint16 { return ADD_I(x) },
+    func(x, y, kk, addr uint16) uint16 { return LDF(x) },
+    func(x, y, kk, addr uint16) uint16 { return LDB(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_I_VX(x) },
+    func(x, y, kk, addr uint16) uint16 { return LD_VX_I(x) },
+}
+
+// fuzzProgram builds a program from fuzz data, with 3 bytes per instruction:
+// the instruction, its registers and its byte. Jumps and calls go to the
+// instruction selected by the byte.
+func fuzzProgram(data []byte) []uint16 {
+    count := len(data) / 3
+    if count > 256 {
+        count = 256
+    }
+    ops := make([]uint16, count)
+    for i := range ops {
+        b := data[i * 3:]
+        x, y, kk := uint16(b[1] >> 4), uint16(b[1] & 0xF), uint16(b[2])
+        addr := ProgramStart + kk % uint16(count) * 2
+        ops[i] = fuzzOps[int(b[0]) % len(fuzzOps)](x, y, kk, addr)
+    }
+    return ops
+}
+
+// fuzzQuirks returns the quirks selected by the bits of b. XO-CHIP is not
+// covered by the reference model.
+func fuzzQuirks(b byte) Quirks {
+    q := Quirks{
+        ShiftVY:        b & 1 != 0,
+        IncrementIndex: b & 2 != 0,
+        JumpVX:         b & 4 != 0,
+        ResetVF:        b & 8 != 0,
+    }
+    if b & 16 != 0 {
+        q.DrawMode = Wrap
+    }
+    return q
+}
+
+// divergence describes the first difference between the CPU and the
+// reference model
+type divergence struct {
+    // steps is the number of instructions executed, including the one at pc
+    // that diverged
+    steps  int
+    pc     uint16
+    opCode uint16
+    diff   string
+}
+
+func (d *divergence) String() string {
+    text, ok := Mnemonic(d.opCode, nil)
+    if !ok {
+        text = "invalid"
+    }
+    return fmt.Sprintf("%s after %d instructions, at 0x%03X: %04X %s", d.diff, d.steps, d.pc, d.opCode, text)
+}
+
+// diverge runs a program on the CPU and the reference model side by side,
+// and returns the first difference, or nil if they agree until the program
+// ends or reaches an instruction the model does not cover
+func diverge(ops []uint16, quirks Quirks, keys uint16) *divergence {
+    c, err := NewCPU(Build(ops...))
+    if err != nil {
+        panic(err)
+    }
+    c.Quirks = quirks
+    c.Seed(1)
+    for k := range c.Keypad {
+        c.Keypad[k] = keys & (1 << k) != 0
+    }
+    r := newReference(c, NewXorShift(1))
+    for step := 1; step <= fuzzSteps; step++ {
+        if step % fuzzTickSteps == 0 {
+            c.TickTimers()
+            r.tick()
+        }
+        pc := r.pc
+        var opCode uint16
+        if int(pc) + 2 <= len(r.memory) {
+            opCode = uint16(r.memory[pc]) << 8 | uint16(r.memory[pc + 1])
+        }
+        expected := r.step()
+        if expected == errNotModelled {
+            return nil
+        }
+        err := c.Cycle()
+        diff := ""
+        if (err == nil) != (expected == nil) {
+            diff = fmt.Sprintf("error %v, reference %v", err, expected)
+        } else {
+            diff = compareReference(c, r)
+        }
+        if diff != "" {
+            return &divergence{steps: step, pc: pc, opCode: opCode, diff: diff}
+        }
+        if err != nil {
+            return nil
+        }
+    }
+    return nil
+}
+
+// compareReference returns the first difference in the registers, memory or
+// display of the CPU and the reference model
+func compareReference(c *CPU, r *reference) string {
+    if c.ProgramCounter != r.pc {
+        return fmt.Sprintf("PC = 0x%03X, reference 0x%03X", c.ProgramCounter, r.pc)
+    }
+    for x := range r.v {
+        if c.Register[x] != r.v[x] {
+            return fmt.Sprintf("V%X = 0x%02X, reference 0x%02X", x, c.Register[x], r.v[x])
+        }
+    }
+    if c.Index != r.i {
+        return fmt.Sprintf("I = 0x%03X, reference 0x%03X", c.Index, r.i)
+    }
+    if c.DelayTimer != r.dt || c.SoundTimer != r.st {
+        return fmt.Sprintf("DT, ST = %d, %d, reference %d, %d", c.DelayTimer, c.SoundTimer, r.dt, r.st)
+    }
+    same := int(c.StackPointer) == len(r.stack)
+    for i := 0; same && i < len(r.stack); i++ {
+        same = c.Stack[i] == r.stack[i]
+    }
+    if !same {
+        return fmt.Sprintf("stack = %03X, reference %03X", c.Stack[:c.StackPointer], r.stack)
+    }
+    if *(*[ClassicMemorySize]byte)(c.Memory[:ClassicMemorySize]) != r.memory {
+        for addr := range r.memory {
+            if c.Memory[addr] != r.memory[addr] {
+                return fmt.Sprintf("memory[0x%03X] = 0x%02X, reference 0x%02X", addr, c.Memory[addr], r.memory[addr])
+            }
+        }
+    }
+    if c.HighRes {
+        return "high resolution, reference low resolution"
+    }
+    for y := range r.screen {
+        for x, lit := range r.screen[y] {
+            if pixel := c.DisplayBuffer[x][y]; (pixel != 0) != lit {
+                return fmt.Sprintf("pixel (%d, %d) = %d, reference %d", x, y, pixel, flag(lit))
+            }
+        }
+    }
+    return ""
+}
+
+// minimize removes instructions from a diverging program, in chunks of
+// halving size, for as long as it keeps diverging
+func minimize(ops []uint16, quirks Quirks, keys uint16) []uint16 {
+    for size := len(ops) / 2; size >= 1; size /= 2 {
+        for i := 0; i + size <= len(ops); {
+            candidate := append(append([]uint16{}, ops[:i]...), ops[i + size:]...)
+            if len(candidate) > 0 && diverge(candidate, quirks, keys) != nil {
+                ops = candidate
+            } else {
+                i += size
+            }
+        }
+    }
+    return ops
+}
+
+// reproducer lists a program with its mnemonics, followed by the Build call
+// that recreates it
+func reproducer(ops []uint16, quirks Quirks, keys uint16) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "quirks %+v, keys %016b\n", quirks, keys)
+    words := make([]string, len(ops))
+    for i, op := range ops {
+        text, ok := Mnemonic(op, nil)
+        if !ok {
+            text = "invalid"
+        }
+        fmt.Fprintf(&b, "    0x%03X  %04X  %s\n", ProgramStart + i * 2, op, text)
+        words[i] = fmt.Sprintf("0x%04X", op)
+    }
+    fmt.Fprintf(&b, "Build(%s)", strings.Join(words, ", "))
+    return b.String()
+}
+
+// checkReference fails with the first divergence of a program and a
+// minimized reproducer
+func checkReference(t *testing.T, ops []uint16, quirks Quirks, keys uint16) {
+    t.Helper()
+    if d := diverge(ops, quirks, keys); d != nil {
+        minimized := minimize(ops, quirks, keys)
+        t.Fatalf("%v\n%s", diverge(minimized, quirks, keys), reproducer(minimized, quirks, keys))
+    }
+}
+
+// Fuzz_Reference compares the CPU with the reference model on fuzzed
+// programs. It runs the seeds with go test, and searches for new
+// divergences with:
+//
+//   go test ./chip8 -run '^$' -fuzz Fuzz_Reference
+func Fuzz_Reference(f *testing.F) {
+    f.Add(byte(0), uint16(0), []byte{
+        7, 0x10, 0x01,
+        7, 0xF0, 0xFF,
+        13, 0xF1, 0x00,
+    })
+    f.Add(byte(0x1F), uint16(0x0001), []byte{
+        7, 0x12, 0x3C,
+        7, 0x21, 0x1E,
+        20, 0x00, 0x00,
+        23, 0x12, 0x04,
+        23, 0x12, 0x04,
+        33, 0x30, 0x00,
+        2, 0x00, 0x00,
+    })
+    f.Fuzz(func(t *testing.T, quirks byte, keys uint16, data []byte) {
+        checkReference(t, fuzzProgram(data), fuzzQuirks(quirks), keys)
+    })
+}
+
+func Test_Reference_random(t *testing.T) {
+    random := NewXorShift(1)
+    data := make([]byte, 64 * 3)
+    for i := 0; i < 2000; i++ {
+        for j := range data {
+            data[j] = random.RandomByte()
+        }
+        checkReference(t, fuzzProgram(data), fuzzQuirks(random.RandomByte()), uint16(random.RandomByte()) << 8)
+    }
+}
@@ -0,0 +1,473 @@
+package chip8
+
+// handlers execute the instructions of every Op. The program counter still
+// points at the instruction, and is advanced past it by execute unless the
+// handler returns an error. Jumps set it to the instruction before their
+// target.
+var handlers = [opCount]func(c *CPU, in *Instruction) error{
+    OpInvalid:     (*CPU).opInvalid,
+    OpSYS:         (*CPU).opSYS,
+    OpCLS:         (*CPU).opCLS,
+    OpRET:         (*CPU).opRET,
+    OpSCD:         (*CPU).opSCD,
+    OpSCU:         (*CPU).opSCU,
+    OpSCR:         (*CPU).opSCR,
+    OpSCL:         (*CPU).opSCL,
+    OpEXIT:        (*CPU).opEXIT,
+    OpLOW:         (*CPU).opLOW,
+    OpHIGH:        (*CPU).opHIGH,
+    OpJP:          (*CPU).opJP,
+    OpCALL:        (*CPU).opCALL,
+    OpSE:          (*CPU).opSE,
+    OpSNE:         (*CPU).opSNE,
+    OpSE_R:        (*CPU).opSE_R,
+    OpSAVE:        (*CPU).opSAVE,
+    OpLOAD:        (*CPU).opLOAD,
+    OpLD:          (*CPU).opLD,
+    OpADD:         (*CPU).opADD,
+    OpLD_R:        (*CPU).opLD_R,
+    OpOR:          (*CPU).opOR,
+    OpAND:         (*CPU).opAND,
+    OpXOR:         (*CPU).opXOR,
+    OpADD_R:       (*CPU).opADD_R,
+    OpSUB:         (*CPU).opSUB,
+    OpSHR:         (*CPU).opSHR,
+    OpSUBN:        (*CPU).opSUBN,
+    OpSHL:         (*CPU).opSHL,
+    OpSNE_R:       (*CPU).opSNE_R,
+    OpLDI:         (*CPU).opLDI,
+    OpJP_R:        (*CPU).opJP_R,
+    OpRND:         (*CPU).opRND,
+    OpDRW:         (*CPU).opDRW,
+    OpSKP:         (*CPU).opSKP,
+    OpSKNP:        (*CPU).opSKNP,
+    OpLD_I_LONG:   (*CPU).opLD_I_LONG,
+    OpPLANE:       (*CPU).opPLANE,
+    OpAUDIO:       (*CPU).opAUDIO,
+    OpLD_VX_DT:    (*CPU).opLD_VX_DT,
+    OpLD_VX_K:     (*CPU).opLD_VX_K,
+    OpLD_DT_VX:    (*CPU).opLD_DT_VX,
+    OpLD_ST_VX:    (*CPU).opLD_ST_VX,
+    OpADD_I:       (*CPU).opADD_I,
+    OpLDF:         (*CPU).opLDF,
+    OpLD_HF_VX:    (*CPU).opLD_HF_VX,
+    OpLDB:         (*CPU).opLDB,
+    OpLD_PITCH_VX: (*CPU).opLD_PITCH_VX,
+    OpLD_I_VX:     (*CPU).opLD_I_VX,
+    OpLD_VX_I:     (*CPU).opLD_VX_I,
+    OpLD_R_VX:     (*CPU).opLD_R_VX,
+    OpLD_VX_R:     (*CPU).opLD_VX_R,
+}
+
+// superChipHandlers are the handlers used when XO-CHIP is disabled. The
+// XO-CHIP instructions are invalid, except for 00Dn which is a SYS call.
+var superChipHandlers = func() [opCount]func(c *CPU, in *Instruction) error {
+    schip := handlers
+    for op := range schip {
+        if Op(op).XOChip() {
+            schip[op] = (*CPU).opInvalid
+        }
+    }
+    schip[OpSCU] = (*CPU).opSYS
+    return schip
+}()
+
+// classicHandlers are the handlers used when SUPER-CHIP is disabled too. Its
+// instructions that start with 0 are SYS calls, the others are invalid.
+var classicHandlers = func() [opCount]func(c *CPU, in *Instruction) error {
+    classic := superChipHandlers
+    for op := range classic {
+        if Op(op).SuperChip() {
+            classic[op] = (*CPU).opInvalid
+        }
+    }
+    for _, op := range []Op{OpSCD, OpSCR, OpSCL, OpEXIT, OpLOW, OpHIGH} {
+        classic[op] = (*CPU).opSYS
+    }
+    return classic
+}()
+
+// handlers returns the handlers of the instructions enabled by the quirks
+func (c *CPU) handlers() *[opCount]func(c *CPU, in *Instruction) error {
+    switch {
+    case c.Quirks.XOChip:
+        return &handlers
+    case c.Quirks.SuperChip:
+        return &superChipHandlers
+    }
+    return &classicHandlers
+}
+
+func (c *CPU) opInvalid(in *Instruction) error {
+    return c.fault(ErrUnknownOpcode, in.OpCode)
+}
+
+// opSYS ignores calls to machine code
+func (c *CPU) opSYS(in *Instruction) error {
+    return nil
+}
+
+func (c *CPU) opCLS(in *Instruction) error {
+    c.clear()
+    return nil
+}
+
+func (c *CPU) opRET(in *Instruction) error {
+    if c.StackPointer == 0 {
+        return c.fault(ErrStackUnderflow, in.OpCode)
+    }
+    c.StackPointer--
+    c.ProgramCounter = c.Stack[c.StackPointer]
+    return nil
+}
+
+func (c *CPU) opSCD(in *Instruction) error {
+    c.scroll(0, int(in.N))
+    return nil
+}
+
+func (c *CPU) opSCU(in *Instruction) error {
+    c.scroll(0, -int(in.N))
+    return nil
+}
+
+func (c *CPU) opSCR(in *Instruction) error {
+    c.scroll(4, 0)
+    return nil
+}
+
+func (c *CPU) opSCL(in *Instruction) error {
+    c.scroll(-4, 0)
+    return nil
+}
+
+func (c *CPU) opEXIT(in *Instruction) error {
+    return c.fault(ErrExit, in.OpCode)
+}
+
+func (c *CPU) opLOW(in *Instruction) error {
+    c.setHighRes(false)
+    return nil
+}
+
+func (c *CPU) opHIGH(in *Instruction) error {
+    c.setHighRes(true)
+    return nil
+}
+
+func (c *CPU) opJP(in *Instruction) error {
+    c.ProgramCounter = in.NNN - 2
+    return nil
+}
+
+func (c *CPU) opCALL(in *Instruction) error {
+    if int(c.StackPointer) >= len(c.Stack) {
+        return c.fault(ErrStackOverflow, in.OpCode)
+    }
+    c.Stack[c.StackPointer] = c.ProgramCounter
+    c.StackPointer++
+    c.ProgramCounter = in.NNN - 2
+    return nil
+}
+
+func (c *CPU) opSE(in *Instruction) error {
+    if c.Register[in.X] == in.NN {
+        c.skip()
+    }
+    return nil
+}
+
+func (c *CPU) opSNE(in *Instruction) error {
+    if c.Register[in.X] != in.NN {
+        c.skip()
+    }
+    return nil
+}
+
+func (c *CPU) opSE_R(in *Instruction) error {
+    if c.Register[in.X] == c.Register[in.Y] {
+        c.skip()
+    }
+    return nil
+}
+
+func (c *CPU) opSAVE(in *Instruction) error {
+    registers := registerRange(uint16(in.X), uint16(in.Y))
+    if !c.inMemory(c.Index, len(registers)) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    for i, r := range registers {
+        c.Memory[int(c.Index) + i] = c.Register[r]
+    }
+    return nil
+}
+
+func (c *CPU) opLOAD(in *Instruction) error {
+    registers := registerRange(uint16(in.X), uint16(in.Y))
+    if !c.inMemory(c.Index, len(registers)) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    for i, r := range registers {
+        c.Register[r] = c.Memory[int(c.Index) + i]
+    }
+    return nil
+}
+
+func (c *CPU) opLD(in *Instruction) error {
+    c.Register[in.X] = in.NN
+    return nil
+}
+
+func (c *CPU) opADD(in *Instruction) error {
+    c.Register[in.X] += in.NN
+    return nil
+}
+
+func (c *CPU) opLD_R(in *Instruction) error {
+    c.Register[in.X] = c.Register[in.Y]
+    return nil
+}
+
+func (c *CPU) opOR(in *Instruction) error {
+    c.Register[in.X] |= c.Register[in.Y]
+    if c.Quirks.ResetVF {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opAND(in *Instruction) error {
+    c.Register[in.X] &= c.Register[in.Y]
+    if c.Quirks.ResetVF {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opXOR(in *Instruction) error {
+    c.Register[in.X] ^= c.Register[in.Y]
+    if c.Quirks.ResetVF {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+// opADD_R, opSUB and opSUBN write the flag after the result, so VF holds the
+// flag even when it is the destination
+func (c *CPU) opADD_R(in *Instruction) error {
+    sum := uint16(c.Register[in.X]) + uint16(c.Register[in.Y])
+    c.Register[in.X] = byte(sum)
+    if sum > 0xFF {
+        c.Register[0xF] = 1
+    } else {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opSUB(in *Instruction) error {
+    x, y := c.Register[in.X], c.Register[in.Y]
+    c.Register[in.X] = x - y
+    if x >= y {
+        c.Register[0xF] = 1
+    } else {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opSHR(in *Instruction) error {
+    value := c.shiftSource(uint16(in.X), uint16(in.Y))
+    c.Register[in.X] = value >> 1
+    c.Register[0xF] = value & 0x1
+    return nil
+}
+
+func (c *CPU) opSUBN(in *Instruction) error {
+    x, y := c.Register[in.X], c.Register[in.Y]
+    c.Register[in.X] = y - x
+    if y >= x {
+        c.Register[0xF] = 1
+    } else {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opSHL(in *Instruction) error {
+    value := c.shiftSource(uint16(in.X), uint16(in.Y))
+    c.Register[in.X] = value << 1
+    c.Register[0xF] = value & 0x80 >> 7
+    return nil
+}
+
+func (c *CPU) opSNE_R(in *Instruction) error {
+    if c.Register[in.X] != c.Register[in.Y] {
+        c.skip()
+    }
+    return nil
+}
+
+func (c *CPU) opLDI(in *Instruction) error {
+    c.Index = in.NNN
+    return nil
+}
+
+func (c *CPU) opJP_R(in *Instruction) error {
+    offset := c.Register[0]
+    if c.Quirks.JumpVX {
+        offset = c.Register[in.X]
+    }
+    c.ProgramCounter = uint16(offset) + in.NNN - 2
+    return nil
+}
+
+func (c *CPU) opRND(in *Instruction) error {
+    c.Register[in.X] = c.randomByte() & in.NN
+    return nil
+}
+
+func (c *CPU) opDRW(in *Instruction) error {
+    width, rows := 8, int(in.N)
+    if rows == 0 && (c.Quirks.SuperChip || c.Quirks.XOChip) {
+        // SUPER-CHIP 16x16 sprite
+        width, rows = 16, 16
+    }
+    if !c.inMemory(c.Index, rows * width / 8 * c.planeCount()) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    // VF is written last, so it can also be used as a coordinate
+    if c.drawSprite(c.Register[in.X], c.Register[in.Y], width, rows) {
+        c.Register[0xF] = 1
+    } else {
+        c.Register[0xF] = 0
+    }
+    return nil
+}
+
+func (c *CPU) opSKP(in *Instruction) error {
+    if c.Keypad[c.Register[in.X] & 0xF] {
+        c.skip()
+    }
+    return nil
+}
+
+func (c *CPU) opSKNP(in *Instruction) error {
+    if !c.Keypad[c.Register[in.X] & 0xF] {
+        c.skip()
+    }
+    return nil
+}
+
+// opLD_I_LONG loads I from the word after the instruction, and skips it
+func (c *CPU) opLD_I_LONG(in *Instruction) error {
+    if !c.inMemory(c.ProgramCounter, 4) {
+        return c.fault(ErrPCOutOfBounds, in.OpCode)
+    }
+    c.Index = c.word(c.ProgramCounter + 2)
+    c.ProgramCounter += 2
+    return nil
+}
+
+func (c *CPU) opPLANE(in *Instruction) error {
+    c.Planes = in.X
+    return nil
+}
+
+func (c *CPU) opAUDIO(in *Instruction) error {
+    if !c.inMemory(c.Index, PatternSize) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    copy(c.AudioPattern[:], c.Memory[c.Index:])
+    return nil
+}
+
+func (c *CPU) opLD_VX_DT(in *Instruction) error {
+    c.Register[in.X] = c.DelayTimer
+    return nil
+}
+
+// opLD_VX_K repeats until a key was pressed and released
+func (c *CPU) opLD_VX_K(in *Instruction) error {
+    if !c.awaitKey(uint16(in.X)) {
+        c.ProgramCounter -= 2
+    }
+    return nil
+}
+
+func (c *CPU) opLD_DT_VX(in *Instruction) error {
+    c.DelayTimer = c.Register[in.X]
+    return nil
+}
+
+func (c *CPU) opLD_ST_VX(in *Instruction) error {
+    c.SoundTimer = c.Register[in.X]
+    return nil
+}
+
+func (c *CPU) opADD_I(in *Instruction) error {
+    c.Index += uint16(c.Register[in.X])
+    return nil
+}
+
+func (c *CPU) opLDF(in *Instruction) error {
+    c.Index = uint16(c.Register[in.X] & 0xF) * 5
+    return nil
+}
+
+func (c *CPU) opLD_HF_VX(in *Instruction) error {
+    c.Index = BigFontStart + uint16(c.Register[in.X] & 0xF) * 10
+    return nil
+}
+
+func (c *CPU) opLDB(in *Instruction) error {
+    if !c.inMemory(c.Index, 3) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    value := c.Register[in.X]
+    c.Memory[c.Index] = value / 100
+    c.Memory[c.Index + 1] = value / 10 % 10
+    c.Memory[c.Index + 2] = value % 10
+    return nil
+}
+
+func (c *CPU) opLD_PITCH_VX(in *Instruction) error {
+    c.Pitch = c.Register[in.X]
+    return nil
+}
+
+func (c *CPU) opLD_I_VX(in *Instruction) error {
+    n := int(in.X) + 1
+    if !c.inMemory(c.Index, n) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    copy(c.Memory[c.Index:], c.Register[:n])
+    if c.Quirks.IncrementIndex {
+        c.Index += uint16(n)
+    }
+    return nil
+}
+
+func (c *CPU) opLD_VX_I(in *Instruction) error {
+    n := int(in.X) + 1
+    if !c.inMemory(c.Index, n) {
+        return c.fault(ErrMemoryOutOfBounds, in.OpCode)
+    }
+    copy(c.Register[:n], c.Memory[c.Index:])
+    if c.Quirks.IncrementIndex {
+        c.Index += uint16(n)
+    }
+    return nil
+}
+
+func (c *CPU) opLD_R_VX(in *Instruction) error {
+    if err := c.storeFlags(uint16(in.X)); err != nil {
+        return c.fault(err, in.OpCode)
+    }
+    return nil
+}
+
+func (c *CPU) opLD_VX_R(in *Instruction) error {
+    copy(c.Register[:in.X + 1], c.Flags[:])
+    return nil
+}
@@ -0,0 +1,39 @@
+package chip8
+
+// KeyCount is the number of keys on the hexadecimal keypad
+const KeyCount = 16
+
+// InputSource provides the state of the hexadecimal keypad. When set on the
+// CPU it is polled at the start of every cycle.
+type InputSource interface {
+    // KeyState returns true for every key that is currently held down
+    KeyState() [KeyCount]bool
+}
+
+// pollInput copies the state of the input source, if any, into the keypad
+func (c *CPU) pollInput() {
+    if c.Input != nil {
+        c.Keypad = c.Input.KeyState()
+    }
+}
+
+// awaitKey implements the blocking behaviour of Fx0A. It returns true once a
+// key has been pressed and released again, after storing that key in Vx.
+func (c *CPU) awaitKey(x uint16) bool {
+    if c.keyHeld {
+        if c.Keypad[c.heldKey] {
+            return false
+        }
+        c.keyHeld = false
+        c.Register[x] = c.heldKey
+        return true
+    }
+    for k, pressed := range c.Keypad {
+        if pressed {
+            c.keyHeld = true
+            c.heldKey = byte(k)
+            break
+        }
+    }
+    return false
+}
@@ -0,0 +1,127 @@
+package chip8
+
+import (
+    "fmt"
+)
+
+// mnemonicFormat formats an instruction in the syntax of the assembler
+type mnemonicFormat func(in *Instruction, addr func(uint16) string) string
+
+// text formats instructions without operands
+func text(s string) mnemonicFormat {
+    return func(*Instruction, func(uint16) string) string {
+        return s
+    }
+}
+
+// address formats instructions with the address nnn
+func address(prefix string) mnemonicFormat {
+    return func(in *Instruction, addr func(uint16) string) string {
+        return prefix + addr(in.NNN)
+    }
+}
+
+// registerByte formats the vX, kk instructions
+func registerByte(name string) mnemonicFormat {
+    return func(in *Instruction, _ func(uint16) string) string {
+        return fmt.Sprintf("%s v%X, 0x%02X", name, in.X, in.NN)
+    }
+}
+
+// registers formats the vX, vY instructions
+func registers(name string) mnemonicFormat {
+    return func(in *Instruction, _ func(uint16) string) string {
+        return fmt.Sprintf("%s v%X, v%X", name, in.X, in.Y)
+    }
+}
+
+// register formats instructions with vX, in a format with a single %X
+func register(format string) mnemonicFormat {
+    return func(in *Instruction, _ func(uint16) string) string {
+        return fmt.Sprintf(format, in.X)
+    }
+}
+
+// nibble formats instructions with the operand n
+func nibble(format string) mnemonicFormat {
+    return func(in *Instruction, _ func(uint16) string) string {
+        return fmt.Sprintf(format, in.N)
+    }
+}
+
+var mnemonics = [opCount]mnemonicFormat{
+    OpSYS:         address("sys "),
+    OpCLS:         text("cls"),
+    OpRET:         text("ret"),
+    OpSCD:         nibble("scd %d"),
+    OpSCU:         nibble("scu %d"),
+    OpSCR:         text("scr"),
+    OpSCL:         text("scl"),
+    OpEXIT:        text("exit"),
+    OpLOW:         text("low"),
+    OpHIGH:        text("high"),
+    OpJP:          address("jp "),
+    OpCALL:        address("call "),
+    OpSE:          registerByte("se"),
+    OpSNE:         registerByte("sne"),
+    OpSE_R:        registers("se"),
+    OpSAVE:        registers("save"),
+    OpLOAD:        registers("load"),
+    OpLD:          registerByte("ld"),
+    OpADD:         registerByte("add"),
+    OpLD_R:        registers("ld"),
+    OpOR:          registers("or"),
+    OpAND:         registers("and"),
+    OpXOR:         registers("xor"),
+    OpADD_R:       registers("add"),
+    OpSUB:         registers("sub"),
+    OpSHR:         registers("shr"),
+    OpSUBN:        registers("subn"),
+    OpSHL:         registers("shl"),
+    OpSNE_R:       registers("sne"),
+    OpLDI:         address("ld i, "),
+    OpJP_R:        address("jp v0, "),
+    OpRND:         registerByte("rnd"),
+    OpDRW: func(in *Instruction, _ func(uint16) string) string {
+        return fmt.Sprintf("drw v%X, v%X, %d", in.X, in.Y, in.N)
+    },
+    OpSKP:         register("skp v%X"),
+    OpSKNP:        register("sknp v%X"),
+    OpLD_I_LONG:   text("ld i, long"),
+    OpPLANE:       register("plane %d"),
+    OpAUDIO:       text("audio"),
+    OpLD_VX_DT:    register("ld v%X, dt"),
+    OpLD_VX_K:     register("ld v%X, k"),
+    OpLD_DT_VX:    register("ld dt, v%X"),
+    OpLD_ST_VX:    register("ld st, v%X"),
+    OpADD_I:       register("add i, v%X"),
+    OpLDF:         register("ld f, v%X"),
+    OpLD_HF_VX:    register("ld hf, v%X"),
+    OpLDB:         register("ld b, v%X"),
+    OpLD_PITCH_VX: register("ld pitch, v%X"),
+    OpLD_I_VX:     register("ld [i], v%X"),
+    OpLD_VX_I:     register("ld v%X, [i]"),
+    OpLD_R_VX:     register("ld r, v%X"),
+    OpLD_VX_R:     register("ld v%X, r"),
+}
+
+// Mnemonic returns the instruction encoded by opCode in the syntax of the
+// assembler, and false if it is not a valid instruction. Addresses are
+// formatted by addr, or as hexadecimal numbers if addr is nil. The XO-CHIP
+// F000 is returned as "ld i, long", as its address is in the next word.
+func Mnemonic(opCode uint16, addr func(uint16) string) (string, bool) {
+    in := Decode(opCode)
+    return in.Mnemonic(addr)
+}
+
+// Mnemonic returns the instruction in the syntax of the assembler, like the
+// function Mnemonic
+func (in Instruction) Mnemonic(addr func(uint16) string) (string, bool) {
+    if in.Op == OpInvalid {
+        return "", false
+    }
+    if addr == nil {
+        addr = func(a uint16) string { return fmt.Sprintf("0x%03X", a) }
+    }
+    return mnemonics[in.Op](&in, addr), true
+}
@@ -0,0 +1,215 @@
+package chip8
+
+// Build encodes a sequence of instructions into program data that can be
+// passed to NewCPU or LoadProgram.
+func Build(ops ...uint16) []byte {
+    data := []byte {}
+    for _, v := range ops {
+        data = append(data, byte(v >> 8))
+        data = append(data, byte(v))
+    }
+    return data
+}
+
+// instruction builders, each returning the encoding of a single opcode
+func CLS() uint16 {
+    return 0x00E0
+}
+
+func CALL(addr uint16) uint16 {
+    return 0x2000 | addr
+}
+
+func NOP() uint16 {
+    return 0x0000
+}
+
+func RET() uint16 {
+    return 0x00EE
+}
+
+func JP(addr uint16) uint16 {
+    return 0x1000 | addr
+}
+
+func SE(index uint16, value uint16) uint16 {
+    return 0x3000 | (index << 8) | (value)
+}
+
+func SNE(index uint16, value uint16) uint16 {
+    return 0x4000 | (index << 8) | (value)
+}
+
+func SE_R(x, y uint16) uint16 {
+    return 0x5000 | (x << 8) | (y << 4)
+}
+
+func LD(index uint16, value uint16) uint16 {
+    return 0x6000 | (index << 8) | (value)
+}
+
+func LD_R(x, y uint16) uint16 {
+    return 0x8000 | (x << 8) | (y << 4)
+}
+
+func ADD(index uint16, value uint16) uint16 {
+    return 0x7000 | (index << 8) | (value)
+}
+
+func OR(x, y uint16) uint16 {
+    return 0x8001 | (x << 8) | (y << 4)
+}
+
+func AND(x,y uint16) uint16 {
+    return 0x8002 | (x << 8) | (y << 4)
+}
+
+func XOR(x, y uint16) uint16 {
+    return 0x8003 | (x << 8) | (y << 4)
+}
+
+func ADD_R(x,y uint16) uint16 {
+    return 0x8004 | (x << 8) | (y << 4)
+}
+
+func SUB(x,y uint16) uint16 {
+    return 0x8005 | (x << 8) | (y << 4)
+}
+
+func SHR(x uint16) uint16 {
+    return 0x8006 | (x << 8)
+}
+
+func SUBN(x,y uint16) uint16 {
+    return 0x8007 | (x << 8) | (y << 4)
+}
+
+func SHL(x uint16) uint16 {
+    return 0x800E | (x << 8)
+}
+
+func SNE_R(x,y uint16) uint16 {
+    return 0x9000 | (x << 8) | (y << 4)
+}
+
+func LDI(value uint16) uint16 {
+    return 0xA000 | value
+}
+
+func JP_R(value uint16) uint16 {
+    return 0xB000 | value
+}
+
+func RND(x, value uint16) uint16 {
+    return 0xC000 | (x << 8) | value
+}
+
+func DRW(x,y,n uint16) uint16 {
+    return 0xD000 | (x << 8) | (y << 4) | n
+}
+
+func SKP(x uint16) uint16 {
+    return 0xE09E | (x << 8)
+}
+
+func SKNP(x uint16) uint16 {
+    return 0xE0A1 | (x << 8)
+}
+
+func LD_VX_DT(x uint16) uint16 {
+    return 0xF007 | (x << 8)
+}
+
+func LD_VX_K(x uint16) uint16 {
+    return 0xF00A | (x << 8)
+}
+
+func LD_DT_VX(x uint16) uint16 {
+    return 0xF015 | (x << 8)
+}
+
+func LD_ST_VX(x uint16) uint16 {
+    return 0xF018 | (x << 8)
+}
+
+func ADD_I(x uint16) uint16 {
+    return 0xF01E | (x << 8)
+}
+
+func LDF(x uint16) uint16 {
+    return 0xF029 | (x << 8)
+}
+
+func LDB(x uint16) uint16 {
+    return 0xF033 | (x << 8)
+}
+
+func LD_I_VX(x uint16) uint16 {
+    return 0xF055 | (x << 8)
+}
+
+func LD_VX_I(x uint16) uint16 {
+    return 0xF065 | (x << 8)
+}
+
+// SUPER-CHIP instructions
+func SCD(n uint16) uint16 {
+    return 0x00C0 | n
+}
+
+func SCR() uint16 {
+    return 0x00FB
+}
+
+func SCL() uint16 {
+    return 0x00FC
+}
+
+func EXIT() uint16 {
+    return 0x00FD
+}
+
+func LOW() uint16 {
+    return 0x00FE
+}
+
+func HIGH() uint16 {
+    return 0x00FF
+}
+
+func LD_HF_VX(x uint16) uint16 {
+    return 0xF030 | (x << 8)
+}
+
+func LD_R_VX(x uint16) uint16 {
+    return 0xF075 | (x << 8)
+}
+
+func LD_VX_R(x uint16) uint16 {
+    return 0xF085 | (x << 8)
+}
+
+// XO-CHIP instructions
+func SCU(n uint16) uint16 {
+    return 0x00D0 | n
+}
+
+func SAVE(x uint16, y uint16) uint16 {
+    return 0x5002 | (x << 8) | (y << 4)
+}
+
+func LOAD(x uint16, y uint16) uint16 {
+    return 0x5003 | (x << 8) | (y << 4)
+}
+
+func PLANE(n uint16) uint16 {
+    return 0xF001 | (n << 8)
+}
+
+func AUDIO() uint16 {
+    return 0xF002
+}
+
+func LD_PITCH_VX(x uint16) uint16 {
+    return 0xF03A | (x << 8)
+}
@@ -0,0 +1,90 @@
+package chip8
+
+import (
+    "sort"
+)
+
+// Quirks selects between the interpretations of ambiguous instructions used
+// by the different CHIP-8 interpreters. The zero value matches the behaviour
+// most modern ROMs expect.
+type Quirks struct {
+    // ShiftVY makes 8xy6 and 8xyE shift Vy and store the result in Vx,
+    // instead of shifting Vx in place
+    ShiftVY bool
+    // IncrementIndex makes Fx55 and Fx65 leave I pointing past the last
+    // register stored or loaded
+    IncrementIndex bool
+    // JumpVX makes Bnnn jump to nnn + Vx, where x is the highest nibble of
+    // nnn, instead of nnn + V0
+    JumpVX bool
+    // ResetVF makes 8xy1, 8xy2 and 8xy3 set VF to 0
+    ResetVF bool
+    // DrawMode selects how sprites crossing the edge of the display are drawn
+    DrawMode DrawMode
+    // SuperChip enables the SUPER-CHIP extensions: high resolution, scrolling,
+    // 00FD exit, the large font, the RPL flags and 16x16 sprites drawn with
+    // Dxy0. Without it 00Cn and 00FB to 00FF are ignored like other machine
+    // code calls, and Dxy0 draws nothing.
+    SuperChip bool
+    // XOChip enables the XO-CHIP extensions: the 64 KiB address space,
+    // bitplanes, audio patterns and the instructions using them. It includes
+    // the SUPER-CHIP extensions.
+    XOChip bool
+}
+
+var (
+    // QuirksModern is the behaviour of most modern interpreters
+    QuirksModern = Quirks{}
+    // QuirksCOSMACVIP is the behaviour of the original interpreter on the
+    // RCA COSMAC VIP
+    QuirksCOSMACVIP = Quirks{
+        ShiftVY:        true,
+        IncrementIndex: true,
+        ResetVF:        true,
+        DrawMode:       Clip,
+    }
+    // QuirksCHIP48 is the behaviour of CHIP-48 on the HP-48 calculators
+    QuirksCHIP48 = Quirks{
+        JumpVX:   true,
+        DrawMode: Clip,
+    }
+    // QuirksSuperChip is the behaviour of SUPER-CHIP 1.1
+    QuirksSuperChip = Quirks{
+        JumpVX:    true,
+        DrawMode:  Clip,
+        SuperChip: true,
+    }
+    // QuirksXOChip is the behaviour of XO-CHIP
+    QuirksXOChip = Quirks{
+        ShiftVY:        true,
+        IncrementIndex: true,
+        DrawMode:       Wrap,
+        SuperChip:      true,
+        XOChip:         true,
+    }
+)
+
+// quirksProfiles maps the names of the quirk profiles to their settings
+var quirksProfiles = map[string]Quirks{
+    "modern": QuirksModern,
+    "vip":    QuirksCOSMACVIP,
+    "chip48": QuirksCHIP48,
+    "schip":  QuirksSuperChip,
+    "xochip": QuirksXOChip,
+}
+
+// QuirksProfile returns the quirks of the named profile
+func QuirksProfile(name string) (Quirks, bool) {
+    q, ok := quirksProfiles[name]
+    return q, ok
+}
+
+// QuirksProfiles returns the names of all quirk profiles, sorted
+func QuirksProfiles() []string {
+    names := make([]string, 0, len(quirksProfiles))
+    for name := range quirksProfiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,50 @@
+package chip8
+
+import (
+    "time"
+)
+
+// RandomSource provides the random bytes used by Cxkk
+type RandomSource interface {
+    RandomByte() byte
+}
+
+// XorShift is a small seedable random source. Its entire state is the State
+// field, so a sequence can be saved and resumed exactly.
+type XorShift struct {
+    State uint32
+}
+
+// NewXorShift returns a random source that produces the same sequence for the
+// same seed
+func NewXorShift(seed int64) *XorShift {
+    state := uint32(seed) ^ uint32(seed >> 32)
+    if state == 0 {
+        // xorshift never leaves the all-zero state
+        state = 0x9E3779B9
+    }
+    return &XorShift{State: state}
+}
+
+func (r *XorShift) RandomByte() byte {
+    x := r.State
+    x ^= x << 13
+    x ^= x >> 17
+    x ^= x << 5
+    r.State = x
+    return byte(x >> 24)
+}
+
+// Seed replaces the random source of the CPU with one seeded from seed
+func (c *CPU) Seed(seed int64) {
+    c.Random = NewXorShift(seed)
+}
+
+// randomByte returns the next byte from the random source, seeding one from
+// the current time if none was set
+func (c *CPU) randomByte() byte {
+    if c.Random == nil {
+        c.Seed(time.Now().UnixNano())
+    }
+    return c.Random.RandomByte()
+}
@@ -0,0 +1,254 @@
+package chip8
+
+import (
+    "errors"
+)
+
+var (
+    // errReferenceFault is returned by the model for instructions that can
+    // not be executed
+    errReferenceFault = errors.New("fault")
+    // errNotModelled is returned for the instructions the model does not
+    // cover, after which it can not be compared with the CPU
+    errNotModelled = errors.New("instruction not modelled")
+)
+
+// reference is a plain model of the CHIP-8 instruction set, written from the
+// instruction descriptions rather than from the CPU, to test the CPU against.
+// It only covers the original instructions in low resolution, with the quirks
+// that change their meaning, and keeps the display as rows of pixels. The
+// SUPER-CHIP and XO-CHIP extensions are disabled.
+type reference struct {
+    quirks Quirks
+    memory [ClassicMemorySize]byte
+    v      [16]byte
+    i      uint16
+    pc     uint16
+    // stack holds the addresses of the calls, like the CPU does
+    stack  []uint16
+    dt     byte
+    st     byte
+    screen [DisplayHeight][DisplayWidth]bool
+    keys   [KeyCount]bool
+    random RandomSource
+    // waiting is set while Fx0A waits for the key in waitKey to be released
+    waiting bool
+    waitKey byte
+}
+
+// newReference returns a model with the same memory as the CPU, which holds
+// the font and the program
+func newReference(c *CPU, random RandomSource) *reference {
+    r := &reference{
+        quirks: c.Quirks,
+        pc:     ProgramStart,
+        keys:   c.Keypad,
+        random: random,
+    }
+    copy(r.memory[:], c.Memory[:])
+    return r
+}
+
+func (r *reference) tick() {
+    if r.dt > 0 {
+        r.dt--
+    }
+    if r.st > 0 {
+        r.st--
+    }
+}
+
+// step executes the instruction at pc. Nothing is changed if it returns an
+// error.
+func (r *reference) step() error {
+    if int(r.pc) + 2 > len(r.memory) {
+        return errReferenceFault
+    }
+    op := uint16(r.memory[r.pc]) << 8 | uint16(r.memory[r.pc + 1])
+    x := op >> 8 & 0xF
+    y := op >> 4 & 0xF
+    n := op & 0xF
+    kk := byte(op)
+    nnn := op & 0xFFF
+    vx, vy := r.v[x], r.v[y]
+    next := r.pc + 2
+
+    switch {
+    case op == 0x00E0:
+        r.screen = [DisplayHeight][DisplayWidth]bool{}
+    case op == 0x00EE:
+        if len(r.stack) == 0 {
+            return errReferenceFault
+        }
+        next = r.stack[len(r.stack) - 1] + 2
+        r.stack = r.stack[:len(r.stack) - 1]
+    case op & 0xF000 == 0x0000:
+        // machine code calls are ignored
+    case op & 0xF000 == 0x1000:
+        next = nnn
+    case op & 0xF000 == 0x2000:
+        if len(r.stack) == 16 {
+            return errReferenceFault
+        }
+        r.stack = append(r.stack, r.pc)
+        next = nnn
+    case op & 0xF000 == 0x3000:
+        if vx == kk {
+            next += 2
+        }
+    case op & 0xF000 == 0x4000:
+        if vx != kk {
+            next += 2
+        }
+    case op & 0xF00F == 0x5000:
+        if vx == vy {
+            next += 2
+        }
+    case op & 0xF000 == 0x6000:
+        r.v[x] = kk
+    case op & 0xF000 == 0x7000:
+        r.v[x] = vx + kk
+    case op & 0xF00F == 0x8000:
+        r.v[x] = vy
+    case op & 0xF00F == 0x8001, op & 0xF00F == 0x8002, op & 0xF00F == 0x8003:
+        switch n {
+        case 1:
+            r.v[x] = vx | vy
+        case 2:
+            r.v[x] = vx & vy
+        case 3:
+            r.v[x] = vx ^ vy
+        }
+        if r.quirks.ResetVF {
+            r.v[0xF] = 0
+        }
+    // the flag of the arithmetic instructions is written after the result,
+    // so it replaces the result when x is F
+    case op & 0xF00F == 0x8004:
+        sum := int(vx) + int(vy)
+        r.v[x] = byte(sum)
+        r.v[0xF] = flag(sum > 0xFF)
+    case op & 0xF00F == 0x8005:
+        r.v[x] = vx - vy
+        r.v[0xF] = flag(vx >= vy)
+    case op & 0xF00F == 0x8007:
+        r.v[x] = vy - vx
+        r.v[0xF] = flag(vy >= vx)
+    case op & 0xF00F == 0x8006, op & 0xF00F == 0x800E:
+        value := vx
+        if r.quirks.ShiftVY {
+            value = vy
+        }
+        if n == 6 {
+            r.v[x] = value >> 1
+            r.v[0xF] = value & 1
+        } else {
+            r.v[x] = value << 1
+            r.v[0xF] = value >> 7
+        }
+    case op & 0xF00F == 0x9000:
+        if vx != vy {
+            next += 2
+        }
+    case op & 0xF000 == 0xA000:
+        r.i = nnn
+    case op & 0xF000 == 0xB000:
+        offset := r.v[0]
+        if r.quirks.JumpVX {
+            offset = vx
+        }
+        next = nnn + uint16(offset)
+    case op & 0xF000 == 0xC000:
+        r.v[x] = r.random.RandomByte() & kk
+    case op & 0xF000 == 0xD000:
+        if int(r.i) + int(n) > len(r.memory) {
+            return errReferenceFault
+        }
+        r.v[0xF] = flag(r.draw(vx, vy, r.memory[r.i:r.i + n]))
+    case op & 0xF0FF == 0xE09E:
+        if r.keys[vx & 0xF] {
+            next += 2
+        }
+    case op & 0xF0FF == 0xE0A1:
+        if !r.keys[vx & 0xF] {
+            next += 2
+        }
+    case op & 0xF0FF == 0xF007:
+        r.v[x] = r.dt
+    case op & 0xF0FF == 0xF00A:
+        next = r.pc
+        if r.waiting && !r.keys[r.waitKey] {
+            r.waiting = false
+            r.v[x] = r.waitKey
+            next = r.pc + 2
+        } else if !r.waiting {
+            for k := range r.keys {
+                if r.keys[k] {
+                    r.waiting = true
+                    r.waitKey = byte(k)
+                    break
+                }
+            }
+        }
+    case op & 0xF0FF == 0xF015:
+        r.dt = vx
+    case op & 0xF0FF == 0xF018:
+        r.st = vx
+    case op & 0xF0FF == 0xF01E:
+        r.i += uint16(vx)
+    case op & 0xF0FF == 0xF029:
+        r.i = uint16(vx & 0xF) * 5
+    case op & 0xF0FF == 0xF033:
+        if int(r.i) + 3 > len(r.memory) {
+            return errReferenceFault
+        }
+        r.memory[r.i] = vx / 100
+        r.memory[r.i + 1] = vx / 10 % 10
+        r.memory[r.i + 2] = vx % 10
+    case op & 0xF0FF == 0xF055, op & 0xF0FF == 0xF065:
+        if int(r.i) + int(x) + 1 > len(r.memory) {
+            return errReferenceFault
+        }
+        for j := uint16(0); j <= x; j++ {
+            if kk == 0x55 {
+                r.memory[r.i + j] = r.v[j]
+            } else {
+                r.v[j] = r.memory[r.i + j]
+            }
+        }
+        if r.quirks.IncrementIndex {
+            r.i += x + 1
+        }
+    default:
+        return errNotModelled
+    }
+    r.pc = next
+    return nil
+}
+
+// draw XORs the sprite onto the screen at (x, y), and returns true if a lit
+// pixel was erased
+func (r *reference) draw(x, y byte, sprite []byte) bool {
+    erased := false
+    for row, bits := range sprite {
+        py := (int(y) % DisplayHeight) + row
+        if py >= DisplayHeight && r.quirks.DrawMode == Clip {
+            break
+        }
+        for col := 0; col < 8; col++ {
+            if bits & (0x80 >> col) == 0 {
+                continue
+            }
+            px := (int(x) % DisplayWidth) + col
+            if px >= DisplayWidth && r.quirks.DrawMode == Clip {
+                break
+            }
+            p := &r.screen[py % DisplayHeight][px % DisplayWidth]
+            if *p {
+                erased = true
+            }
+            *p = !*p
+        }
+    }
+    return erased
+}
@@ -0,0 +1,108 @@
+package chip8
+
+import (
+    "io/ioutil"
+    "os"
+)
+
+const (
+    // HiResWidth is the width of the display in SUPER-CHIP high resolution
+    HiResWidth = 128
+    // HiResHeight is the height of the display in SUPER-CHIP high resolution
+    HiResHeight = 64
+    // BigFontStart is the address of the SUPER-CHIP large font
+    BigFontStart = 0x50
+    // FlagCount is the number of RPL user flags saved by Fx75
+    FlagCount = 16
+)
+
+// bigFontSet contains the SUPER-CHIP large hexadecimal font, 10 bytes per
+// character, which is loaded after the regular font
+var bigFontSet = []byte {
+    0x3C,0x7E,0xE7,0xC3,0xC3,0xC3,0xC3,0xE7,0x7E,0x3C, // "0"
+    0x18,0x38,0x58,0x18,0x18,0x18,0x18,0x18,0x18,0x3C, // "1"
+    0x3E,0x7F,0xC3,0x06,0x0C,0x18,0x30,0x60,0xFF,0xFF, // "2"
+    0x3C,0x7E,0xC3,0x03,0x0E,0x0E,0x03,0xC3,0x7E,0x3C, // "3"
+    0x06,0x0E,0x1E,0x36,0x66,0xC6,0xFF,0xFF,0x06,0x06, // "4"
+    0xFF,0xFF,0xC0,0xC0,0xFC,0xFE,0x03,0xC3,0x7E,0x3C, // "5"
+    0x3E,0x7C,0xE0,0xC0,0xFC,0xFE,0xC3,0xC3,0x7E,0x3C, // "6"
+    0xFF,0xFF,0x03,0x06,0x0C,0x18,0x30,0x60,0x60,0x60, // "7"
+    0x3C,0x7E,0xC3,0xC3,0x7E,0x7E,0xC3,0xC3,0x7E,0x3C, // "8"
+    0x3C,0x7E,0xC3,0xC3,0x7F,0x3F,0x03,0x03,0x3E,0x7C, // "9"
+    0x18,0x3C,0x66,0xC3,0xC3,0xFF,0xFF,0xC3,0xC3,0xC3, // "A"
+    0xFC,0xFE,0xC3,0xC3,0xFE,0xFE,0xC3,0xC3,0xFE,0xFC, // "B"
+    0x3C,0x7E,0xC3,0xC0,0xC0,0xC0,0xC0,0xC3,0x7E,0x3C, // "C"
+    0xFC,0xFE,0xC3,0xC3,0xC3,0xC3,0xC3,0xC3,0xFE,0xFC, // "D"
+    0xFF,0xFF,0xC0,0xC0,0xFC,0xFC,0xC0,0xC0,0xFF,0xFF, // "E"
+    0xFF,0xFF,0xC0,0xC0,0xFC,0xFC,0xC0,0xC0,0xC0,0xC0, // "F"
+}
+
+// FlagStore persists the RPL user flags of Fx75 and Fx85 between runs, as
+// the HP-48 calculators did
+type FlagStore interface {
+    LoadFlags() ([FlagCount]byte, error)
+    SaveFlags(flags [FlagCount]byte) error
+}
+
+// FlagFile is a FlagStore that keeps the flags in the named file
+type FlagFile string
+
+// LoadFlags returns the flags in the file, or all zeros if it does not exist
+func (f FlagFile) LoadFlags() ([FlagCount]byte, error) {
+    var flags [FlagCount]byte
+    data, err := ioutil.ReadFile(string(f))
+    if os.IsNotExist(err) {
+        return flags, nil
+    }
+    if err != nil {
+        return flags, err
+    }
+    copy(flags[:], data)
+    return flags, nil
+}
+
+func (f FlagFile) SaveFlags(flags [FlagCount]byte) error {
+    return ioutil.WriteFile(string(f), flags[:], 0644)
+}
+
+// setHighRes switches between the low and high resolution and clears the
+// display
+func (c *CPU) setHighRes(highRes bool) {
+    c.HighRes = highRes
+    c.DisplayBuffer = [HiResWidth][HiResHeight]byte{}
+}
+
+// scroll moves the selected planes of the display right by dx columns and
+// down by dy rows. Negative values move left or up, and pixels moved in from
+// outside the display are unlit.
+func (c *CPU) scroll(dx, dy int) {
+    width, height := c.Resolution()
+    planes := c.planes()
+    moved := c.DisplayBuffer
+    for x := 0; x < width; x++ {
+        for y := 0; y < height; y++ {
+            var pixel byte
+            if sx, sy := x - dx, y - dy; sx >= 0 && sx < width && sy >= 0 && sy < height {
+                pixel = c.DisplayBuffer[sx][sy]
+            }
+            moved[x][y] = moved[x][y] &^ planes | pixel & planes
+        }
+    }
+    c.DisplayBuffer = moved
+}
+
+// storeFlags implements Fx75, saving V0 to Vx in the RPL user flags. The
+// flags are only changed once the FlagStore saved them.
+func (c *CPU) storeFlags(x uint16) error {
+    flags := c.Flags
+    for i := uint16(0); i <= x; i++ {
+        flags[i] = c.Register[i]
+    }
+    if c.FlagStore != nil {
+        if err := c.FlagStore.SaveFlags(flags); err != nil {
+            return err
+        }
+    }
+    c.Flags = flags
+    return nil
+}
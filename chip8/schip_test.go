@@ -0,0 +1,285 @@
+package chip8
+
+import (
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// newSuperChipCPU returns a test CPU with the SUPER-CHIP instructions enabled
+func newSuperChipCPU(ops ...uint16) *CPU {
+    c := NewTestCPU(ops...)
+    c.Quirks.SuperChip = true
+    return c
+}
+
+func Test_HIGH_LOW(t *testing.T) {
+    c := newSuperChipCPU(
+        HIGH(),
+        LOW(),
+    )
+    c.DisplayBuffer[1][1] = 1
+
+    c.Cycle()
+    if !c.HighRes {
+        t.Error("expected high resolution")
+    }
+    if w, h := c.Resolution(); w != 128 || h != 64 {
+        t.Errorf("unexpected resolution: %dx%d", w, h)
+    }
+    if c.DisplayBuffer[1][1] != 0 {
+        t.Error("display should be cleared when switching resolution")
+    }
+
+    c.Cycle()
+    if c.HighRes {
+        t.Error("expected low resolution")
+    }
+    if w, h := c.Resolution(); w != 64 || h != 32 {
+        t.Errorf("unexpected resolution: %dx%d", w, h)
+    }
+}
+
+func Test_DRW_high_resolution(t *testing.T) {
+    c := newSuperChipCPU(
+        HIGH(),
+        LD(0x1, 100),
+        LD(0x2, 60),
+        LDI(0x300),
+        DRW(0x1, 0x2, 1),
+    )
+    c.Memory[0x300] = 0xFF
+
+    for i := 0; i < 5; i++ {
+        c.Cycle()
+    }
+
+    for x := 100; x < 108; x++ {
+        if c.DisplayBuffer[x][60] != 1 {
+            t.Errorf("expected pixel at %d,60", x)
+        }
+    }
+}
+
+func Test_DRW_16x16(t *testing.T) {
+    c := newSuperChipCPU(
+        HIGH(),
+        LD(0x1, 120),
+        LD(0x2, 56),
+        LDI(0x300),
+        DRW(0x1, 0x2, 0),
+        DRW(0x1, 0x2, 0),
+    )
+    for i := 0; i < 32; i++ {
+        c.Memory[0x300 + i] = 0xFF
+    }
+
+    for i := 0; i < 5; i++ {
+        c.Cycle()
+    }
+
+    // the sprite is clipped at the right and bottom edge
+    lit := 0
+    for x := 0; x < HiResWidth; x++ {
+        for y := 0; y < HiResHeight; y++ {
+            lit += int(c.DisplayBuffer[x][y])
+        }
+    }
+    if lit != 8 * 8 || c.DisplayBuffer[127][63] != 1 {
+        t.Errorf("unexpected number of lit pixels: %d", lit)
+    }
+    if c.Register[0xF] != 0 {
+        t.Error("VF should not be set")
+    }
+
+    c.Cycle()
+    if c.Register[0xF] != 1 {
+        t.Error("VF should be set on collision")
+    }
+}
+
+func Test_SCD(t *testing.T) {
+    c := newSuperChipCPU(
+        SCD(3),
+    )
+    c.DisplayBuffer[5][0] = 1
+    c.DisplayBuffer[5][30] = 1
+
+    c.Cycle()
+
+    if c.DisplayBuffer[5][0] != 0 || c.DisplayBuffer[5][3] != 1 {
+        t.Error("display was not scrolled down")
+    }
+    if c.DisplayBuffer[5][33] != 0 {
+        t.Error("pixels scrolled past the bottom should be dropped")
+    }
+}
+
+func Test_SCR_SCL(t *testing.T) {
+    c := newSuperChipCPU(
+        SCR(),
+        SCL(),
+        SCL(),
+    )
+    c.DisplayBuffer[0][7] = 1
+    c.DisplayBuffer[62][7] = 1
+
+    c.Cycle()
+    if c.DisplayBuffer[4][7] != 1 || c.DisplayBuffer[0][7] != 0 {
+        t.Error("display was not scrolled right")
+    }
+    if c.DisplayBuffer[66][7] != 0 {
+        t.Error("pixels scrolled past the right edge should be dropped")
+    }
+
+    c.Cycle()
+    c.Cycle()
+    if c.DisplayBuffer[0][7] != 0 || c.DisplayBuffer[4][7] != 0 {
+        t.Error("display was not scrolled left")
+    }
+}
+
+func Test_EXIT(t *testing.T) {
+    c := newSuperChipCPU(
+        EXIT(),
+    )
+
+    assertExecutionError(t, c, ErrExit, 0x200)
+}
+
+func Test_SuperChip_profiles(t *testing.T) {
+    program := []uint16{
+        HIGH(),
+        SCD(0x2),
+        LDI(0x0),
+        DRW(0x0, 0x0, 0),
+        EXIT(),
+        LD(0x5, 0x1),
+    }
+
+    // the SUPER-CHIP instructions are machine code calls, and Dxy0 draws
+    // nothing
+    vip, _ := QuirksProfile("vip")
+    c := NewTestCPU(program...)
+    c.Quirks = vip
+    for i := 0; i < len(program); i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if c.HighRes || c.Register[0x5] != 1 || c.DisplayBuffer != [HiResWidth][HiResHeight]byte{} {
+        t.Errorf("unexpected vip state: high resolution %v, V5 %d", c.HighRes, c.Register[0x5])
+    }
+
+    schip, _ := QuirksProfile("schip")
+    c = NewTestCPU(program...)
+    c.Quirks = schip
+    for i := 0; i < 4; i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if !c.HighRes || c.DisplayBuffer[0][0] != 1 {
+        t.Errorf("unexpected schip state: high resolution %v", c.HighRes)
+    }
+    assertExecutionError(t, c, ErrExit, 0x208)
+
+    for _, op := range []uint16{LD_HF_VX(0x0), LD_R_VX(0x0), LD_VX_R(0x0)} {
+        c := NewTestCPU(op)
+        c.Quirks = vip
+        assertExecutionError(t, c, ErrUnknownOpcode, 0x200)
+    }
+}
+
+func Test_LD_HF_VX(t *testing.T) {
+    c := newSuperChipCPU(
+        LD(0x1, 0xA),
+        LD_HF_VX(0x1),
+    )
+
+    c.Cycle()
+    c.Cycle()
+
+    if c.Index != BigFontStart + 100 {
+        t.Errorf("unexpected index: %03X", c.Index)
+    }
+    if c.Memory[c.Index] != 0x18 {
+        t.Error("large font is not loaded")
+    }
+}
+
+type testFlagStore struct {
+    flags [FlagCount]byte
+    err   error
+}
+
+func (s *testFlagStore) LoadFlags() ([FlagCount]byte, error) {
+    return s.flags, s.err
+}
+
+func (s *testFlagStore) SaveFlags(flags [FlagCount]byte) error {
+    s.flags = flags
+    return s.err
+}
+
+func Test_LD_R_VX(t *testing.T) {
+    c := newSuperChipCPU(
+        LD(0x0, 0x11),
+        LD(0x1, 0x22),
+        LD(0x2, 0x33),
+        LD_R_VX(0x1),
+        LD(0x0, 0x00),
+        LD(0x1, 0x00),
+        LD_VX_R(0x1),
+    )
+    store := &testFlagStore{}
+    c.FlagStore = store
+
+    for i := 0; i < 7; i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if c.Register[0x0] != 0x11 || c.Register[0x1] != 0x22 {
+        t.Errorf("flags were not restored: %v", c.Register[:2])
+    }
+    if store.flags[0x0] != 0x11 || store.flags[0x1] != 0x22 || store.flags[0x2] != 0 {
+        t.Errorf("unexpected saved flags: %v", store.flags)
+    }
+
+    store.err = errors.New("disk full")
+    c.Register[0x0] = 0x55
+    c.ProgramCounter = 0x206
+    if err := c.Cycle(); !errors.Is(err, store.err) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if c.Flags[0x0] != 0x11 || c.ProgramCounter != 0x206 {
+        t.Errorf("the CPU changed after a failed save: flags %v, pc %03X", c.Flags[:2], c.ProgramCounter)
+    }
+}
+
+func Test_FlagFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "chip8")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    store := FlagFile(filepath.Join(dir, "game.flags"))
+
+    flags, err := store.LoadFlags()
+    if err != nil || flags != [FlagCount]byte{} {
+        t.Errorf("missing file should load as zeros: %v %v", flags, err)
+    }
+
+    flags[3] = 42
+    if err := store.SaveFlags(flags); err != nil {
+        t.Fatal(err)
+    }
+    loaded, err := store.LoadFlags()
+    if err != nil || loaded != flags {
+        t.Errorf("unexpected flags: %v %v", loaded, err)
+    }
+}
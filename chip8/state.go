@@ -0,0 +1,458 @@
+package chip8
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+)
+
+// StateVersion is the version of the save state format written by
+// WriteState. Older states, from before the SUPER-CHIP high resolution mode,
+// XO-CHIP and the compact format, can still be read.
+const StateVersion = 4
+
+// stateMagic identifies save states
+var stateMagic = [4]byte{'C', '8', 'S', 'T'}
+
+var (
+    // ErrInvalidState is returned when reading data that is not a save state
+    ErrInvalidState = errors.New("invalid save state")
+    // ErrStateVersion is returned when reading a save state of an unknown
+    // version
+    ErrStateVersion = errors.New("unsupported save state version")
+)
+
+// random source kinds in a save state
+const (
+    randomNone byte = iota
+    randomXorShift
+)
+
+// quirk flags in a save state. quirkClassic is set when the SUPER-CHIP
+// instructions are disabled, so states from before the quirk keep running
+// them.
+const (
+    quirkShiftVY byte = 1 << iota
+    quirkIncrementIndex
+    quirkJumpVX
+    quirkResetVF
+    quirkXOChip
+    quirkClassic
+)
+
+// stateHeader starts every save state
+type stateHeader struct {
+    Magic   [4]byte
+    Version uint16
+}
+
+// stateV1 is the low resolution machine state, in the order it is written
+type stateV1 struct {
+    Memory         [ClassicMemorySize]byte
+    Register       [16]byte
+    Index          uint16
+    DelayTimer     byte
+    SoundTimer     byte
+    ProgramCounter uint16
+    StackPointer   byte
+    Stack          [16]uint16
+    // Display holds one bit per pixel, row by row
+    Display        [DisplayWidth * DisplayHeight / 8]byte
+    // Keypad holds one bit per key
+    Keypad         uint16
+    KeyHeld        bool
+    HeldKey        byte
+    Quirks         byte
+    DrawMode       byte
+    RandomKind     byte
+    RandomState    uint32
+}
+
+// stateV2 is the SUPER-CHIP machine state, in the order it is written
+type stateV2 struct {
+    Memory         [ClassicMemorySize]byte
+    Register       [16]byte
+    Index          uint16
+    DelayTimer     byte
+    SoundTimer     byte
+    ProgramCounter uint16
+    StackPointer   byte
+    Stack          [16]uint16
+    // Display holds one bit per pixel of the high resolution display, row
+    // by row
+    Display        [HiResWidth * HiResHeight / 8]byte
+    HighRes        bool
+    Flags          [FlagCount]byte
+    // Keypad holds one bit per key
+    Keypad         uint16
+    KeyHeld        bool
+    HeldKey        byte
+    Quirks         byte
+    DrawMode       byte
+    RandomKind     byte
+    RandomState    uint32
+}
+
+// upgrade converts a version 1 state, placing the low resolution display
+// at the top left of the high resolution one
+func (s *stateV1) upgrade() *stateV2 {
+    u := &stateV2{
+        Memory:         s.Memory,
+        Register:       s.Register,
+        Index:          s.Index,
+        DelayTimer:     s.DelayTimer,
+        SoundTimer:     s.SoundTimer,
+        ProgramCounter: s.ProgramCounter,
+        StackPointer:   s.StackPointer,
+        Stack:          s.Stack,
+        Keypad:         s.Keypad,
+        KeyHeld:        s.KeyHeld,
+        HeldKey:        s.HeldKey,
+        Quirks:         s.Quirks,
+        DrawMode:       s.DrawMode,
+        RandomKind:     s.RandomKind,
+        RandomState:    s.RandomState,
+    }
+    for y := 0; y < DisplayHeight; y++ {
+        for x := 0; x < DisplayWidth; x++ {
+            i := y * DisplayWidth + x
+            if s.Display[i / 8] & (0x80 >> (i % 8)) != 0 {
+                j := y * HiResWidth + x
+                u.Display[j / 8] |= 0x80 >> (j % 8)
+            }
+        }
+    }
+    return u
+}
+
+// stateV3 is the complete machine state, in the order it is written. Newer
+// states are read into it too.
+type stateV3 struct {
+    Memory         [MemorySize]byte
+    Register       [16]byte
+    Index          uint16
+    DelayTimer     byte
+    SoundTimer     byte
+    ProgramCounter uint16
+    StackPointer   byte
+    Stack          [16]uint16
+    // Display holds one bit per pixel of the high resolution display for
+    // every plane, row by row
+    Display        [PlaneCount][HiResWidth * HiResHeight / 8]byte
+    HighRes        bool
+    Planes         byte
+    AudioPattern   [PatternSize]byte
+    Pitch          byte
+    Flags          [FlagCount]byte
+    // Keypad holds one bit per key
+    Keypad         uint16
+    KeyHeld        bool
+    HeldKey        byte
+    Quirks         byte
+    DrawMode       byte
+    RandomKind     byte
+    RandomState    uint32
+}
+
+// upgrade converts a version 2 state, which only has the first plane and
+// the classic address space
+func (s *stateV2) upgrade() *stateV3 {
+    u := &stateV3{
+        Register:       s.Register,
+        Index:          s.Index,
+        DelayTimer:     s.DelayTimer,
+        SoundTimer:     s.SoundTimer,
+        ProgramCounter: s.ProgramCounter,
+        StackPointer:   s.StackPointer,
+        Stack:          s.Stack,
+        HighRes:        s.HighRes,
+        Planes:         1,
+        Pitch:          DefaultPitch,
+        Flags:          s.Flags,
+        Keypad:         s.Keypad,
+        KeyHeld:        s.KeyHeld,
+        HeldKey:        s.HeldKey,
+        Quirks:         s.Quirks,
+        DrawMode:       s.DrawMode,
+        RandomKind:     s.RandomKind,
+        RandomState:    s.RandomState,
+    }
+    copy(u.Memory[:], s.Memory[:])
+    u.Display[0] = s.Display
+    return u
+}
+
+// stateV4 is the fixed part of the compact machine state, in the order it
+// is written. It is followed by MemorySize bytes of memory, and by the
+// display planes selected by DisplayPlanes at the resolution of HighRes,
+// with one bit per pixel, row by row.
+type stateV4 struct {
+    Register       [16]byte
+    Index          uint16
+    DelayTimer     byte
+    SoundTimer     byte
+    ProgramCounter uint16
+    StackPointer   byte
+    Stack          [16]uint16
+    HighRes        bool
+    Planes         byte
+    AudioPattern   [PatternSize]byte
+    Pitch          byte
+    Flags          [FlagCount]byte
+    // Keypad holds one bit per key
+    Keypad         uint16
+    KeyHeld        bool
+    HeldKey        byte
+    Quirks         byte
+    DrawMode       byte
+    RandomKind     byte
+    RandomState    uint32
+    // MemorySize is the size of the address space in use, the classic
+    // memory unless XO-CHIP is enabled
+    MemorySize     uint32
+    // DisplayPlanes has a bit for every plane with lit pixels, the other
+    // planes are not written
+    DisplayPlanes  byte
+}
+
+// read reads the memory and display following the fixed part of the state,
+// and returns the complete state
+func (s *stateV4) read(r io.Reader) (*stateV3, error) {
+    if s.MemorySize > MemorySize || s.DisplayPlanes >= 1 << PlaneCount {
+        return nil, ErrInvalidState
+    }
+    u := &stateV3{
+        Register:       s.Register,
+        Index:          s.Index,
+        DelayTimer:     s.DelayTimer,
+        SoundTimer:     s.SoundTimer,
+        ProgramCounter: s.ProgramCounter,
+        StackPointer:   s.StackPointer,
+        Stack:          s.Stack,
+        HighRes:        s.HighRes,
+        Planes:         s.Planes,
+        AudioPattern:   s.AudioPattern,
+        Pitch:          s.Pitch,
+        Flags:          s.Flags,
+        Keypad:         s.Keypad,
+        KeyHeld:        s.KeyHeld,
+        HeldKey:        s.HeldKey,
+        Quirks:         s.Quirks,
+        DrawMode:       s.DrawMode,
+        RandomKind:     s.RandomKind,
+        RandomState:    s.RandomState,
+    }
+    if _, err := io.ReadFull(r, u.Memory[:s.MemorySize]); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+    }
+    width, height := DisplayWidth, DisplayHeight
+    if s.HighRes {
+        width, height = HiResWidth, HiResHeight
+    }
+    plane := make([]byte, width * height / 8)
+    for p := range u.Display {
+        if s.DisplayPlanes & (1 << p) == 0 {
+            continue
+        }
+        if _, err := io.ReadFull(r, plane); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+        }
+        for y := 0; y < height; y++ {
+            for x := 0; x < width; x++ {
+                if i := y * width + x; plane[i / 8] & (0x80 >> (i % 8)) != 0 {
+                    j := y * HiResWidth + x
+                    u.Display[p][j / 8] |= 0x80 >> (j % 8)
+                }
+            }
+        }
+    }
+    return u, nil
+}
+
+// readState reads the state following the header of the given version
+func readState(r io.Reader, version uint16) (*stateV3, error) {
+    switch version {
+    case 1:
+        var s stateV1
+        if err := binary.Read(r, binary.BigEndian, &s); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+        }
+        return s.upgrade().upgrade(), nil
+    case 2:
+        var s stateV2
+        if err := binary.Read(r, binary.BigEndian, &s); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+        }
+        return s.upgrade(), nil
+    case 3:
+        var s stateV3
+        if err := binary.Read(r, binary.BigEndian, &s); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+        }
+        return &s, nil
+    case 4:
+        var s stateV4
+        if err := binary.Read(r, binary.BigEndian, &s); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+        }
+        return s.read(r)
+    }
+    return nil, fmt.Errorf("%w: %d", ErrStateVersion, version)
+}
+
+// WriteState writes the complete machine state, including keypad, quirks,
+// flags and the state of the random source if it is a *XorShift. Only the
+// address space in use and the planes with lit pixels are written, at the
+// current resolution, to keep states small.
+func (c *CPU) WriteState(w io.Writer) error {
+    s := &stateV4{
+        Register:       c.Register,
+        Index:          c.Index,
+        DelayTimer:     c.DelayTimer,
+        SoundTimer:     c.SoundTimer,
+        ProgramCounter: c.ProgramCounter,
+        StackPointer:   c.StackPointer,
+        Stack:          c.Stack,
+        KeyHeld:        c.keyHeld,
+        HeldKey:        c.heldKey,
+        DrawMode:       byte(c.Quirks.DrawMode),
+        HighRes:        c.HighRes,
+        Planes:         c.Planes,
+        AudioPattern:   c.AudioPattern,
+        Pitch:          c.Pitch,
+        Flags:          c.Flags,
+        MemorySize:     uint32(c.memorySize()),
+    }
+    width, height := c.Resolution()
+    planes := make([][]byte, PlaneCount)
+    for p := range planes {
+        planes[p] = make([]byte, width * height / 8)
+        for y := 0; y < height; y++ {
+            for x := 0; x < width; x++ {
+                if c.DisplayBuffer[x][y] & (1 << p) != 0 {
+                    i := y * width + x
+                    planes[p][i / 8] |= 0x80 >> (i % 8)
+                    s.DisplayPlanes |= 1 << p
+                }
+            }
+        }
+    }
+    for k, pressed := range c.Keypad {
+        if pressed {
+            s.Keypad |= 1 << k
+        }
+    }
+    flags := []struct {
+        set  bool
+        flag byte
+    }{
+        {c.Quirks.ShiftVY, quirkShiftVY},
+        {c.Quirks.IncrementIndex, quirkIncrementIndex},
+        {c.Quirks.JumpVX, quirkJumpVX},
+        {c.Quirks.ResetVF, quirkResetVF},
+        {c.Quirks.XOChip, quirkXOChip},
+        {!c.Quirks.SuperChip, quirkClassic},
+    }
+    for _, f := range flags {
+        if f.set {
+            s.Quirks |= f.flag
+        }
+    }
+    if r, ok := c.Random.(*XorShift); ok {
+        s.RandomKind = randomXorShift
+        s.RandomState = r.State
+    }
+
+    if err := binary.Write(w, binary.BigEndian, stateHeader{stateMagic, StateVersion}); err != nil {
+        return err
+    }
+    if err := binary.Write(w, binary.BigEndian, s); err != nil {
+        return err
+    }
+    if _, err := w.Write(c.Memory[:s.MemorySize]); err != nil {
+        return err
+    }
+    for p, plane := range planes {
+        if s.DisplayPlanes & (1 << p) != 0 {
+            if _, err := w.Write(plane); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// ReadState restores a machine state written by WriteState. The input
+// source is kept, as is the random source if the state does not contain
+// one. If the state can not be read the CPU is left unchanged.
+func (c *CPU) ReadState(r io.Reader) error {
+    var h stateHeader
+    if err := binary.Read(r, binary.BigEndian, &h); err != nil || h.Magic != stateMagic {
+        return ErrInvalidState
+    }
+    s, err := readState(r, h.Version)
+    if err != nil {
+        return err
+    }
+    if s.StackPointer > byte(len(s.Stack)) || s.HeldKey >= KeyCount || s.DrawMode > byte(Wrap) {
+        return ErrInvalidState
+    }
+
+    c.Memory = s.Memory
+    c.memoryVersion++
+    c.Register = s.Register
+    c.Index = s.Index
+    c.DelayTimer = s.DelayTimer
+    c.SoundTimer = s.SoundTimer
+    c.ProgramCounter = s.ProgramCounter
+    c.StackPointer = s.StackPointer
+    c.Stack = s.Stack
+    c.keyHeld = s.KeyHeld
+    c.heldKey = s.HeldKey
+    c.HighRes = s.HighRes
+    c.Planes = s.Planes
+    c.AudioPattern = s.AudioPattern
+    c.Pitch = s.Pitch
+    c.Flags = s.Flags
+    for y := 0; y < HiResHeight; y++ {
+        for x := 0; x < HiResWidth; x++ {
+            i := y * HiResWidth + x
+            c.DisplayBuffer[x][y] = 0
+            for p := range s.Display {
+                c.DisplayBuffer[x][y] |= (s.Display[p][i / 8] >> (7 - i % 8) & 1) << p
+            }
+        }
+    }
+    for k := range c.Keypad {
+        c.Keypad[k] = s.Keypad & (1 << k) != 0
+    }
+    c.Quirks = Quirks{
+        ShiftVY:        s.Quirks & quirkShiftVY != 0,
+        IncrementIndex: s.Quirks & quirkIncrementIndex != 0,
+        JumpVX:         s.Quirks & quirkJumpVX != 0,
+        ResetVF:        s.Quirks & quirkResetVF != 0,
+        DrawMode:       DrawMode(s.DrawMode),
+        SuperChip:      s.Quirks & quirkClassic == 0,
+        XOChip:         s.Quirks & quirkXOChip != 0,
+    }
+    if s.RandomKind == randomXorShift {
+        c.Random = &XorShift{State: s.RandomState}
+    }
+    return nil
+}
+
+// MarshalBinary returns the machine state as written by WriteState
+func (c *CPU) MarshalBinary() ([]byte, error) {
+    var b bytes.Buffer
+    if err := c.WriteState(&b); err != nil {
+        return nil, err
+    }
+    return b.Bytes(), nil
+}
+
+// UnmarshalBinary restores a machine state returned by MarshalBinary
+func (c *CPU) UnmarshalBinary(data []byte) error {
+    return c.ReadState(bytes.NewReader(data))
+}
@@ -0,0 +1,181 @@
+package chip8
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "testing"
+)
+
+func Test_State_round_trip(t *testing.T) {
+    program := []uint16{
+        CALL(0x204),
+        NOP(),
+        LD(0x1, 0x5),
+        LDI(0x0),
+        DRW(0x1, 0x1, 5),
+        LD_VX_K(0x2),
+        RND(0x3, 0xFF),
+        RET(),
+    }
+    c := NewTestCPU(program...)
+    c.Seed(7)
+    c.Quirks = QuirksXOChip
+    c.DelayTimer = 10
+    c.SoundTimer = 20
+    for i := 0; i < 5; i++ {
+        c.Cycle()
+    }
+    c.Keypad[0xB] = true
+    c.Cycle()
+
+    data, err := c.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    restored := NewTestCPU()
+    if err := restored.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+
+    if restored.Memory != c.Memory || restored.Register != c.Register || restored.Index != c.Index ||
+        restored.ProgramCounter != c.ProgramCounter || restored.StackPointer != c.StackPointer ||
+        restored.Stack != c.Stack || restored.DelayTimer != 10 || restored.SoundTimer != 20 {
+        t.Error("cpu state was not restored")
+    }
+    if restored.DisplayBuffer != c.DisplayBuffer {
+        t.Error("display was not restored")
+    }
+    if restored.Keypad != c.Keypad {
+        t.Error("keypad was not restored")
+    }
+    if restored.Quirks != QuirksXOChip {
+        t.Errorf("quirks were not restored: %+v", restored.Quirks)
+    }
+
+    // the key held for Fx0A and the random sequence continue where they
+    // left off
+    for _, cpu := range []*CPU{c, restored} {
+        cpu.Keypad[0xB] = false
+        cpu.Cycle()
+        cpu.Cycle()
+    }
+    if restored.Register[0x2] != 0xB {
+        t.Errorf("held key was not restored: %v", restored.Register[0x2])
+    }
+    if restored.Register[0x3] != c.Register[0x3] {
+        t.Error("random source was not restored")
+    }
+}
+
+func Test_State_invalid(t *testing.T) {
+    c := NewTestCPU(LD(0x1, 0x2))
+    c.Cycle()
+
+    if err := c.UnmarshalBinary([]byte("garbage")); !errors.Is(err, ErrInvalidState) {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    data, _ := c.MarshalBinary()
+    data[5] = 99
+    if err := c.UnmarshalBinary(data); !errors.Is(err, ErrStateVersion) {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    data, _ = c.MarshalBinary()
+    if err := c.ReadState(bytes.NewReader(data[:100])); !errors.Is(err, ErrInvalidState) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if c.Register[0x1] != 0x2 || c.ProgramCounter != 0x202 {
+        t.Error("cpu should not change when the state can not be read")
+    }
+}
+
+func Test_State_high_resolution(t *testing.T) {
+    c := newSuperChipCPU(HIGH())
+    c.Cycle()
+    c.DisplayBuffer[127][63] = 1
+    c.Flags[2] = 9
+
+    data, _ := c.MarshalBinary()
+    restored := NewTestCPU()
+    if err := restored.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+
+    if !restored.HighRes || restored.DisplayBuffer != c.DisplayBuffer || restored.Flags != c.Flags {
+        t.Error("high resolution state was not restored")
+    }
+}
+
+func Test_State_version_1(t *testing.T) {
+    s := stateV1{ProgramCounter: 0x204}
+    s.Register[0x3] = 7
+    // pixel 63,1
+    s.Display[(DisplayWidth + 63) / 8] = 0x01
+    var b bytes.Buffer
+    binary.Write(&b, binary.BigEndian, stateHeader{stateMagic, 1})
+    binary.Write(&b, binary.BigEndian, &s)
+
+    c := NewTestCPU()
+    c.HighRes = true
+    if err := c.ReadState(&b); err != nil {
+        t.Fatal(err)
+    }
+
+    if c.ProgramCounter != 0x204 || c.Register[0x3] != 7 || c.HighRes {
+        t.Error("cpu state was not restored")
+    }
+    if c.DisplayBuffer[63][1] != 1 || c.DisplayBuffer[64][1] != 0 {
+        t.Error("display was not restored")
+    }
+}
+
+func Test_State_size(t *testing.T) {
+    c, _ := NewCPU(nil)
+    data, _ := c.MarshalBinary()
+    if len(data) > ClassicMemorySize + 512 {
+        t.Errorf("state of a classic CPU is %d bytes", len(data))
+    }
+
+    // the XO-CHIP memory and the second plane are only written when used
+    c.Quirks = QuirksXOChip
+    c.Memory[MemorySize - 1] = 0x42
+    c.DisplayBuffer[1][2] = 2
+    data, _ = c.MarshalBinary()
+    if len(data) < MemorySize || len(data) > MemorySize + 512 {
+        t.Errorf("state of an XO-CHIP CPU is %d bytes", len(data))
+    }
+    restored := NewTestCPU()
+    if err := restored.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+    if restored.Memory != c.Memory || restored.DisplayBuffer != c.DisplayBuffer {
+        t.Error("XO-CHIP state was not restored")
+    }
+}
+
+func Test_State_version_3(t *testing.T) {
+    s := stateV3{ProgramCounter: 0x204, Planes: 3, Pitch: DefaultPitch}
+    s.Memory[MemorySize - 1] = 0x42
+    // pixel 127,63 on the second plane
+    s.Display[1][len(s.Display[1]) - 1] = 0x01
+    s.HighRes = true
+    s.Quirks = quirkXOChip
+    var b bytes.Buffer
+    binary.Write(&b, binary.BigEndian, stateHeader{stateMagic, 3})
+    binary.Write(&b, binary.BigEndian, &s)
+
+    c := NewTestCPU()
+    if err := c.ReadState(&b); err != nil {
+        t.Fatal(err)
+    }
+
+    if c.ProgramCounter != 0x204 || c.Memory[MemorySize - 1] != 0x42 || c.Planes != 3 || !c.Quirks.XOChip {
+        t.Error("cpu state was not restored")
+    }
+    if c.DisplayBuffer[127][63] != 2 {
+        t.Error("display was not restored")
+    }
+}
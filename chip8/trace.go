@@ -0,0 +1,35 @@
+package chip8
+
+// TraceState is the part of the machine state recorded by a Tracer
+type TraceState struct {
+    ProgramCounter uint16
+    // OpCode is the instruction at the program counter, or 0 if the program
+    // counter is out of bounds
+    OpCode         uint16
+    Register       [16]byte
+    Index          uint16
+    StackPointer   byte
+    Stack          [16]uint16
+}
+
+// Tracer records the instructions executed by a CPU
+type Tracer interface {
+    // Trace is called by Cycle with the state before and after the
+    // instruction, and the error returned by Cycle, if any
+    Trace(before, after *TraceState, err error)
+}
+
+// traceState returns the current state for the tracer
+func (c *CPU) traceState() TraceState {
+    s := TraceState{
+        ProgramCounter: c.ProgramCounter,
+        Register:       c.Register,
+        Index:          c.Index,
+        StackPointer:   c.StackPointer,
+        Stack:          c.Stack,
+    }
+    if c.inMemory(c.ProgramCounter, 2) {
+        s.OpCode = c.word(c.ProgramCounter)
+    }
+    return s
+}
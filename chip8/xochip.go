@@ -0,0 +1,81 @@
+package chip8
+
+import (
+    "math"
+)
+
+const (
+    // PlaneCount is the number of XO-CHIP bitplanes. Every byte of the
+    // DisplayBuffer holds one bit per plane, so a pixel has 4 colors.
+    PlaneCount = 2
+    // PatternSize is the size of the XO-CHIP audio pattern buffer in bytes
+    PatternSize = 16
+    // DefaultPitch is the initial Fx3A pitch, which plays the audio pattern
+    // at 4000 bits per second
+    DefaultPitch = 64
+)
+
+// planes returns the bitmask of the planes drawn to, which is always the
+// first plane unless XO-CHIP is enabled
+func (c *CPU) planes() byte {
+    if !c.Quirks.XOChip {
+        return 1
+    }
+    return c.Planes & (1 << PlaneCount - 1)
+}
+
+// planeCount returns the number of selected planes
+func (c *CPU) planeCount() int {
+    n := 0
+    for p := c.planes(); p != 0; p >>= 1 {
+        n += int(p & 1)
+    }
+    return n
+}
+
+// clear turns off the selected planes of the display
+func (c *CPU) clear() {
+    planes := c.planes()
+    for x := range c.DisplayBuffer {
+        for y := range c.DisplayBuffer[x] {
+            c.DisplayBuffer[x][y] &^= planes
+        }
+    }
+}
+
+// skip skips the next instruction, which is 4 bytes long if it is the
+// XO-CHIP F000 nnnn
+func (c *CPU) skip() {
+    next := c.ProgramCounter + 2
+    if c.Quirks.XOChip && c.inMemory(c.ProgramCounter, 4) && c.word(next) == 0xF000 {
+        c.ProgramCounter += 2
+    }
+    c.ProgramCounter += 2
+}
+
+// word returns the big endian 16-bit value at addr
+func (c *CPU) word(addr uint16) uint16 {
+    return uint16(c.Memory[addr]) << 8 | uint16(c.Memory[int(addr) + 1])
+}
+
+// registerRange returns the registers of 5xy2 and 5xy3 in the order they are
+// stored, which is descending if x is greater than y
+func registerRange(x, y uint16) []uint16 {
+    step := 1
+    if x > y {
+        step = -1
+    }
+    registers := []uint16{}
+    for r := int(x); ; r += step {
+        registers = append(registers, uint16(r))
+        if r == int(y) {
+            return registers
+        }
+    }
+}
+
+// PatternRate returns the rate at which the audio pattern is played, in bits
+// per second
+func (c *CPU) PatternRate() float64 {
+    return 4000 * math.Pow(2, (float64(c.Pitch) - 64) / 48)
+}
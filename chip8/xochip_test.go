@@ -0,0 +1,228 @@
+package chip8
+
+import (
+    "math"
+    "testing"
+)
+
+func NewXOChipCPU(ops ...uint16) *CPU {
+    c := NewTestCPU(ops...)
+    c.Quirks = QuirksXOChip
+    return c
+}
+
+func Test_LD_I_long(t *testing.T) {
+    c := NewXOChipCPU(
+        0xF000, 0xE123,
+        LD(0x1, 0x5),
+    )
+
+    c.Cycle()
+    if c.Index != 0xE123 || c.ProgramCounter != 0x204 {
+        t.Errorf("unexpected state: i %04X, pc %03X", c.Index, c.ProgramCounter)
+    }
+}
+
+func Test_skip_long(t *testing.T) {
+    c := NewXOChipCPU(
+        SE(0x0, 0x0),
+        0xF000, 0xE123,
+        SE(0x0, 0x1),
+        0xF000, 0xE123,
+    )
+
+    c.Cycle()
+    if c.ProgramCounter != 0x206 {
+        t.Errorf("should skip the whole 4 byte instruction: %03X", c.ProgramCounter)
+    }
+    c.Cycle()
+    c.Cycle()
+    if c.Index != 0xE123 {
+        t.Errorf("should not skip: %04X", c.Index)
+    }
+}
+
+func Test_LD_I_long_end_of_memory(t *testing.T) {
+    c := NewXOChipCPU()
+    c.Memory[0xFFFE] = 0xF0
+    c.Memory[0x0000] = 0x12
+    c.Memory[0x0001] = 0x34
+    c.ProgramCounter = 0xFFFE
+
+    assertExecutionError(t, c, ErrPCOutOfBounds, 0xFFFE)
+    if c.Index != 0 {
+        t.Errorf("i should not be loaded from the start of memory: %04X", c.Index)
+    }
+}
+
+func Test_skip_long_end_of_memory(t *testing.T) {
+    c := NewXOChipCPU()
+    c.Memory[0xFFFE] = 0x30
+    c.Memory[0x0000] = 0xF0
+    c.Memory[0x0001] = 0x00
+    c.ProgramCounter = 0xFFFE
+
+    c.Cycle()
+
+    // there is no word after the end of memory to check for F000, so the
+    // word at 0 is not skipped as well
+    if c.ProgramCounter != 0x0002 {
+        t.Errorf("unexpected pc: %04X", c.ProgramCounter)
+    }
+}
+
+func Test_XOChip_memory(t *testing.T) {
+    c := NewXOChipCPU(
+        0xF000, 0xFFF0,
+        LD(0x1, 0x42),
+        LD_I_VX(0x1),
+    )
+    for i := 0; i < 3; i++ {
+        if err := c.Cycle(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if c.Memory[0xFFF1] != 0x42 {
+        t.Error("memory above 4 KiB should be writable")
+    }
+
+    // the classic address space ends at 4 KiB
+    c = NewTestCPU(
+        LDI(0xFFF),
+        LD_I_VX(0x1),
+    )
+    c.Cycle()
+    assertExecutionError(t, c, ErrMemoryOutOfBounds, 0x202)
+}
+
+func Test_XOChip_disabled(t *testing.T) {
+    for _, op := range []uint16{0xF000, SAVE(0x1, 0x2), LOAD(0x1, 0x2), PLANE(2), AUDIO(), LD_PITCH_VX(0x1)} {
+        c := NewTestCPU(op)
+        assertExecutionError(t, c, ErrUnknownOpcode, 0x200)
+    }
+}
+
+func Test_SAVE_LOAD(t *testing.T) {
+    c := NewXOChipCPU(
+        LD(0x1, 0x11),
+        LD(0x2, 0x22),
+        LD(0x3, 0x33),
+        LDI(0x300),
+        SAVE(0x1, 0x3),
+        LDI(0x310),
+        SAVE(0x3, 0x1),
+        LDI(0x300),
+        LOAD(0x6, 0x4),
+    )
+    for i := 0; i < 9; i++ {
+        c.Cycle()
+    }
+
+    if c.Memory[0x300] != 0x11 || c.Memory[0x301] != 0x22 || c.Memory[0x302] != 0x33 {
+        t.Errorf("unexpected memory: % X", c.Memory[0x300:0x303])
+    }
+    if c.Memory[0x310] != 0x33 || c.Memory[0x312] != 0x11 {
+        t.Errorf("registers should be stored in reverse: % X", c.Memory[0x310:0x313])
+    }
+    if c.Register[0x6] != 0x11 || c.Register[0x5] != 0x22 || c.Register[0x4] != 0x33 {
+        t.Errorf("unexpected registers: % X", c.Register[0x4:0x7])
+    }
+    if c.Index != 0x300 {
+        t.Error("I should not change")
+    }
+}
+
+func Test_PLANE_DRW(t *testing.T) {
+    c := NewXOChipCPU(
+        PLANE(3),
+        LDI(0x300),
+        DRW(0x0, 0x0, 1),
+        PLANE(2),
+        DRW(0x0, 0x0, 1),
+    )
+    c.Memory[0x300] = 0xC0
+    c.Memory[0x301] = 0x60
+
+    for i := 0; i < 3; i++ {
+        c.Cycle()
+    }
+    // the sprite for the second plane follows the first
+    if c.DisplayBuffer[0][0] != 1 || c.DisplayBuffer[1][0] != 3 || c.DisplayBuffer[2][0] != 2 {
+        t.Errorf("unexpected pixels: %v", c.DisplayBuffer[0][:1])
+    }
+
+    c.Cycle()
+    c.Cycle()
+    if c.DisplayBuffer[0][0] != 3 || c.DisplayBuffer[1][0] != 1 || c.DisplayBuffer[2][0] != 2 {
+        t.Errorf("should only draw on the second plane: %v %v %v", c.DisplayBuffer[0][0], c.DisplayBuffer[1][0], c.DisplayBuffer[2][0])
+    }
+    if c.Register[0xF] != 1 {
+        t.Error("VF should be set on collision")
+    }
+}
+
+func Test_PLANE_CLS_scroll(t *testing.T) {
+    c := NewXOChipCPU(
+        PLANE(2),
+        SCU(1),
+        CLS(),
+    )
+    c.DisplayBuffer[0][1] = 3
+
+    c.Cycle()
+    c.Cycle()
+    if c.DisplayBuffer[0][0] != 2 || c.DisplayBuffer[0][1] != 1 {
+        t.Errorf("should only scroll the second plane: %v", c.DisplayBuffer[0][:2])
+    }
+
+    c.Cycle()
+    if c.DisplayBuffer[0][0] != 0 || c.DisplayBuffer[0][1] != 1 {
+        t.Errorf("should only clear the second plane: %v", c.DisplayBuffer[0][:2])
+    }
+}
+
+func Test_AUDIO_pitch(t *testing.T) {
+    c := NewXOChipCPU(
+        LDI(0x300),
+        AUDIO(),
+        LD(0x1, 112),
+        LD_PITCH_VX(0x1),
+    )
+    for i := 0; i < 16; i++ {
+        c.Memory[0x300 + i] = byte(i)
+    }
+
+    if c.PatternRate() != 4000 {
+        t.Errorf("unexpected default rate: %v", c.PatternRate())
+    }
+    for i := 0; i < 4; i++ {
+        c.Cycle()
+    }
+
+    if c.AudioPattern[15] != 15 {
+        t.Errorf("pattern was not loaded: % X", c.AudioPattern)
+    }
+    if c.Pitch != 112 || math.Abs(c.PatternRate() - 8000) > 1e-9 {
+        t.Errorf("unexpected rate: %v", c.PatternRate())
+    }
+}
+
+func Test_State_XOChip(t *testing.T) {
+    c := NewXOChipCPU(PLANE(3))
+    c.Cycle()
+    c.DisplayBuffer[3][4] = 2
+    c.Memory[0xF000] = 0x99
+    c.AudioPattern[0] = 0xAA
+    c.Pitch = 10
+
+    data, _ := c.MarshalBinary()
+    restored := NewTestCPU()
+    if err := restored.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+
+    if restored.Quirks != QuirksXOChip || restored.Planes != 3 || restored.DisplayBuffer != c.DisplayBuffer ||
+        restored.Memory != c.Memory || restored.AudioPattern != c.AudioPattern || restored.Pitch != 10 {
+        t.Error("xo-chip state was not restored")
+    }
+}
@@ -0,0 +1,297 @@
+// Command chip8-headless runs a rom without opening a window, for use in CI.
+// It runs for a fixed number of frames or instructions with scripted input,
+// then writes the display as PNG or text, and optionally compares it to a
+// golden file. It exits with status 1 on errors and mismatches.
+//
+// With -trace every executed instruction is written to a file, to compare
+// the execution against other emulators. With -movie a movie recorded by the
+// chip8 command is played back, with the settings it was recorded with.
+// Otherwise the rom is looked up in the rom database like in the chip8
+// command, and the quirks and speed of its entry are used unless they are
+// set with -quirks and -speed.
+//
+// It does not depend on OpenGL or a display, unlike the chip8 command.
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "chip8-emulator/chip8"
+    "chip8-emulator/headless"
+    "chip8-emulator/movie"
+    "chip8-emulator/romdb"
+    "chip8-emulator/trace"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+func main() {
+    os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+    fs := flag.NewFlagSet("chip8-headless", flag.ContinueOnError)
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8-headless [flags] <rom>")
+        fs.PrintDefaults()
+    }
+    frames := fs.Int("frames", 0, "number of 60 Hz frames to run")
+    cycles := fs.Int("cycles", 0, "number of instructions to run, instead of frames")
+    speed := fs.Int("speed", chip8.DefaultClockSpeed, "clock speed in instructions per second")
+    inputPath := fs.String("input", "", "input script with lines of <frame> press|release <key>")
+    quirksName := fs.String("quirks", "modern", "quirk profile, one of: " + strings.Join(chip8.QuirksProfiles(), ", "))
+    seed := fs.Int64("seed", 1, "seed for the random number generator")
+    output := fs.String("o", "-", "output file for the display, - for standard output")
+    format := fs.String("format", "", "output format, png or text, by default based on the output or golden file")
+    scale := fs.Int("scale", 1, "size of a single pixel in PNG output")
+    golden := fs.String("golden", "", "golden file to compare the display with")
+    update := fs.Bool("update", false, "write the display to the golden file instead of comparing")
+    tracePath := fs.String("trace", "", "file to write a trace of the executed instructions to")
+    traceFormat := fs.String("trace-format", "json", "trace format, json or text")
+    traceRanges := fs.String("trace-range", "", "comma separated hexadecimal address ranges to trace, such as 200-2FF")
+    engine := fs.String("engine", "interpreter", "execution engine, interpreter or blocks, which caches decoded blocks of instructions")
+    moviePath := fs.String("movie", "", "movie to play back, which sets the input, quirks, seed and speed")
+    romdbPath := fs.String("romdb", "", "rom database to read on top of the bundled one and that of the user")
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return 2
+    }
+    // the quirks and speed set on the command line or by the movie take
+    // precedence over the rom database
+    set := map[string]bool{}
+    fs.Visit(func(f *flag.Flag) {
+        set[f.Name] = true
+    })
+    var m *movie.Movie
+    if *moviePath != "" {
+        var err error
+        if m, err = movie.ReadFile(*moviePath); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        *quirksName = m.Quirks
+        *seed = m.Seed
+        *speed = m.ClockSpeed
+        set["quirks"], set["speed"] = true, true
+        if *frames == 0 && *cycles == 0 {
+            *frames = len(m.Frames)
+        }
+    }
+    if (*frames > 0) == (*cycles > 0) {
+        fmt.Fprintln(os.Stderr, "expected either -frames or -cycles")
+        return 2
+    }
+    if *speed <= 0 || *scale <= 0 {
+        fmt.Fprintln(os.Stderr, "speed and scale must be positive")
+        return 2
+    }
+    if _, ok := chip8.QuirksProfile(*quirksName); !ok {
+        fmt.Fprintf(os.Stderr, "unknown quirk profile: %q\n", *quirksName)
+        return 2
+    }
+    if *format == "" {
+        *format = formatOf(*golden, *output)
+    }
+    if *format != "png" && *format != "text" {
+        fmt.Fprintf(os.Stderr, "unknown format: %q\n", *format)
+        return 2
+    }
+    if *engine != "interpreter" && *engine != "blocks" {
+        fmt.Fprintf(os.Stderr, "unknown engine: %q\n", *engine)
+        return 2
+    }
+    tracer, err := newTracer(*traceFormat, *traceRanges)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+
+    romPath := fs.Arg(0)
+    program, err := ioutil.ReadFile(romPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    if m != nil {
+        if err := m.Check(program); err != nil {
+            fmt.Fprintf(os.Stderr, "%s: %v\n", *moviePath, err)
+            return 1
+        }
+    }
+    var paths []string
+    if *romdbPath != "" {
+        paths = append(paths, *romdbPath)
+    }
+    db, err := romdb.Open(paths...)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    settings := romdb.Settings{
+        Quirks:     *quirksName,
+        Speed:      *speed,
+        KeepQuirks: set["quirks"],
+        KeepSpeed:  set["speed"],
+    }
+    db.Select(program, &settings)
+    quirks, _ := chip8.QuirksProfile(settings.Quirks)
+    c, err := chip8.NewCPUWithQuirks(program, quirks)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", romPath, err)
+        return 1
+    }
+    c.Seed(*seed)
+
+    if *tracePath != "" {
+        f, err := os.Create(*tracePath)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        w := bufio.NewWriter(f)
+        defer func() {
+            if err := w.Flush(); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+            }
+            f.Close()
+        }()
+        tracer.W = w
+        c.Tracer = tracer
+    }
+
+    var events []headless.Event
+    if m != nil {
+        events = headless.MovieEvents(m)
+    } else if *inputPath != "" {
+        f, err := os.Open(*inputPath)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        events, err = headless.ParseScript(f)
+        f.Close()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "%s: %v\n", *inputPath, err)
+            return 1
+        }
+    }
+
+    runner := headless.NewRunner(c, settings.Speed, events)
+    if *engine == "blocks" {
+        runner.Executor = chip8.NewBlockCache(c)
+    }
+    if *frames > 0 {
+        err = runner.RunFrames(*frames)
+    } else {
+        err = runner.RunCycles(*cycles)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v after %d instructions\n", romPath, err, runner.Cycles)
+        return 1
+    }
+    if tracer.Err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", *tracePath, tracer.Err)
+        return 1
+    }
+
+    var display bytes.Buffer
+    if *format == "png" {
+        if err := headless.WritePNG(&display, c, *scale, chip8.DefaultPalette); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+    } else {
+        display.WriteString(headless.Text(c))
+    }
+
+    if err := writeOutput(*output, display.Bytes()); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    if *golden == "" {
+        return 0
+    }
+    if *update {
+        if err := ioutil.WriteFile(*golden, display.Bytes(), 0644); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        return 0
+    }
+    return compare(*golden, *format, display.Bytes())
+}
+
+// formatOf picks the output format from the extension of the golden or
+// output file
+func formatOf(paths ...string) string {
+    for _, path := range paths {
+        if strings.EqualFold(filepath.Ext(path), ".png") {
+            return "png"
+        }
+    }
+    return "text"
+}
+
+func writeOutput(path string, data []byte) error {
+    if path == "-" {
+        _, err := os.Stdout.Write(data)
+        return err
+    }
+    if path == "" {
+        return nil
+    }
+    return ioutil.WriteFile(path, data, 0644)
+}
+
+// compare checks the display against the golden file, and returns the exit
+// status
+func compare(golden, format string, display []byte) int {
+    expected, err := ioutil.ReadFile(golden)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    equal := bytes.Equal(expected, display)
+    if format == "png" {
+        if equal, err = headless.EqualPNG(expected, display); err != nil {
+            fmt.Fprintf(os.Stderr, "%s: %v\n", golden, err)
+            return 1
+        }
+    }
+    if !equal {
+        fmt.Fprintf(os.Stderr, "display does not match %s\n", golden)
+        if format == "text" {
+            fmt.Fprintf(os.Stderr, "expected:\n%s\nactual:\n%s", expected, display)
+        }
+        return 1
+    }
+    return 0
+}
+
+// newTracer returns a trace writer, without output, for the trace format and
+// comma separated address ranges
+func newTracer(format, ranges string) (*trace.Writer, error) {
+    f, err := trace.ParseFormat(format)
+    if err != nil {
+        return nil, err
+    }
+    t := trace.NewWriter(nil, f)
+    if ranges == "" {
+        return t, nil
+    }
+    for _, s := range strings.Split(ranges, ",") {
+        r, err := trace.ParseRange(s)
+        if err != nil {
+            return nil, err
+        }
+        t.Ranges = append(t.Ranges, r)
+    }
+    return t, nil
+}
@@ -0,0 +1,151 @@
+// Command chip8-term runs a rom in a terminal, for use over SSH and where
+// OpenGL is not available. The display is drawn with Unicode half blocks in
+// 24-bit color, so the terminal needs at least 64 columns and 16 lines, or
+// 128 by 32 for high resolution programs.
+//
+// The keypad is mapped onto the keys 1-4, Q-R, A-F and Z-V, like in the chip8
+// command. Terminals do not report when a key is released, so a typed key is
+// held for a number of frames, set with -hold. The sound is played as the
+// terminal bell. Ctrl-C quits.
+//
+// The rom is looked up in the rom database like in the chip8 command, and
+// the quirks and speed of its entry are used unless they are set with
+// -quirks and -speed.
+package main
+
+import (
+    "chip8-emulator/chip8"
+    "chip8-emulator/romdb"
+    "chip8-emulator/terminal"
+    "errors"
+    "flag"
+    "fmt"
+    "golang.org/x/term"
+    "io/ioutil"
+    "os"
+    "strings"
+    "time"
+)
+
+const bell = "\a"
+
+func main() {
+    fs := flag.NewFlagSet("chip8-term", flag.ContinueOnError)
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8-term [flags] <rom>")
+        fs.PrintDefaults()
+    }
+    speed := fs.Int("speed", chip8.DefaultClockSpeed, "clock speed in instructions per second")
+    quirksName := fs.String("quirks", "modern", "quirk profile, one of: " + strings.Join(chip8.QuirksProfiles(), ", "))
+    seed := fs.Int64("seed", 0, "seed for the random number generator, 0 for a random seed")
+    hold := fs.Int("hold", terminal.DefaultHold, "number of frames a typed key is held down")
+    mute := fs.Bool("mute", false, "do not ring the bell when the sound plays")
+    romdbPath := fs.String("romdb", "", "rom database to read on top of the bundled one and that of the user")
+    if err := fs.Parse(os.Args[1:]); err != nil {
+        os.Exit(2)
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        os.Exit(2)
+    }
+    if *speed <= 0 || *hold <= 0 {
+        fmt.Fprintln(os.Stderr, "speed and hold must be positive")
+        os.Exit(2)
+    }
+    if _, ok := chip8.QuirksProfile(*quirksName); !ok {
+        fmt.Fprintf(os.Stderr, "unknown quirk profile: %q\n", *quirksName)
+        os.Exit(2)
+    }
+    // the quirks and speed set on the command line take precedence over the
+    // rom database
+    set := map[string]bool{}
+    fs.Visit(func(f *flag.Flag) {
+        set[f.Name] = true
+    })
+
+    romPath := fs.Arg(0)
+    program, err := ioutil.ReadFile(romPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    var paths []string
+    if *romdbPath != "" {
+        paths = append(paths, *romdbPath)
+    }
+    db, err := romdb.Open(paths...)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    settings := romdb.Settings{
+        Quirks:     *quirksName,
+        Speed:      *speed,
+        KeepQuirks: set["quirks"],
+        KeepSpeed:  set["speed"],
+    }
+    db.Select(program, &settings)
+    quirks, _ := chip8.QuirksProfile(settings.Quirks)
+    c, err := chip8.NewCPUWithQuirks(program, quirks)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", romPath, err)
+        os.Exit(1)
+    }
+    if *seed != 0 {
+        c.Seed(*seed)
+    }
+
+    if err := run(c, settings.Speed, *hold, *mute); err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", romPath, err)
+        os.Exit(1)
+    }
+}
+
+// run shows the emulation in the terminal until Ctrl-C is typed or the
+// program exits. The terminal is restored before an error is returned.
+func run(c *chip8.CPU, speed, hold int, mute bool) error {
+    stdin := int(os.Stdin.Fd())
+    if !term.IsTerminal(stdin) {
+        return errors.New("standard input is not a terminal")
+    }
+    state, err := term.MakeRaw(stdin)
+    if err != nil {
+        return err
+    }
+    defer term.Restore(stdin, state)
+
+    keyboard := terminal.NewKeyboard(os.Stdin)
+    keyboard.Hold = hold
+    c.Input = keyboard
+    display := terminal.NewRenderer(os.Stdout, chip8.DefaultPalette)
+    defer display.Close()
+
+    scheduler := chip8.NewScheduler(c, speed)
+    ticker := time.NewTicker(time.Second / chip8.TimerFrequency)
+    defer ticker.Stop()
+
+    playing := false
+    for {
+        select {
+        case <-keyboard.Quit():
+            return nil
+        case <-ticker.C:
+        }
+        keyboard.Frame()
+        err := scheduler.Frame()
+        if errors.Is(err, chip8.ErrExit) {
+            return nil
+        }
+        // the last frame is kept on screen, below the error
+        if err := display.Show(c.Frame()); err != nil {
+            return err
+        }
+        if err != nil {
+            return err
+        }
+        if c.SoundTimer > 0 && !playing && !mute {
+            os.Stdout.WriteString(bell)
+        }
+        playing = c.SoundTimer > 0
+    }
+}
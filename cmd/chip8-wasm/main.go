@@ -0,0 +1,236 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command chip8-wasm runs the emulator in a web page, to share rom demos on a
+// static site. It is built with the Go WebAssembly toolchain and served with
+// index.html and the wasm_exec.js of the same Go release:
+//
+//   GOOS=js GOARCH=wasm go build -o chip8.wasm ./cmd/chip8-wasm
+//   cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// Go releases before 1.24 keep wasm_exec.js in misc/wasm instead. Roms are
+// opened with the file picker, or loaded from the page with ?rom=<url>. The
+// quirk profile is picked in the page, or set with &quirks=<profile>.
+// Otherwise the roms in the bundled rom database run with the quirks and
+// clock speed of their entry, and the others with the modern quirks.
+//
+// The keypad is mapped onto the keys 1-4, Q-R, A-F and Z-V, like in the chip8
+// command, and the sound plays as a square wave.
+package main
+
+import (
+    "chip8-emulator/audio"
+    "chip8-emulator/chip8"
+    "chip8-emulator/romdb"
+    "chip8-emulator/web"
+    "fmt"
+    "syscall/js"
+)
+
+var (
+    document = js.Global().Get("document")
+    screen   = document.Call("getElementById", "screen")
+    context  = screen.Call("getContext", "2d")
+    status   = document.Call("getElementById", "status")
+    quirks   = document.Call("getElementById", "quirks")
+    // database is the bundled rom database, as the page can not read that
+    // of the user
+    database = romdb.Bundled()
+    // quirksChosen is set once the quirks were picked in the page or the
+    // url, after which they are used instead of those of the database
+    quirksChosen bool
+
+    emulator *web.Emulator
+    tone     *speaker
+)
+
+func main() {
+    params := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search"))
+    selected := params.Call("get", "quirks")
+    for _, name := range chip8.QuirksProfiles() {
+        option := document.Call("createElement", "option")
+        option.Set("value", name)
+        option.Set("textContent", name)
+        if name == "modern" && selected.IsNull() || name == selected.String() {
+            option.Set("selected", true)
+        }
+        quirks.Call("appendChild", option)
+    }
+    quirksChosen = !selected.IsNull()
+    quirks.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+        quirksChosen = true
+        return nil
+    }))
+
+    document.Call("getElementById", "rom").Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+        files := this.Get("files")
+        if files.Length() == 0 {
+            return nil
+        }
+        file := files.Index(0)
+        then(file.Call("arrayBuffer"), func(buffer js.Value) {
+            load(file.Get("name").String(), web.Bytes(buffer))
+        })
+        return nil
+    }))
+    if url := params.Call("get", "rom"); !url.IsNull() {
+        then(js.Global().Call("fetch", url), func(response js.Value) {
+            if !response.Get("ok").Bool() {
+                setStatus(fmt.Sprintf("%s: %s", url.String(), response.Get("statusText").String()))
+                return
+            }
+            then(response.Call("arrayBuffer"), func(buffer js.Value) {
+                load(url.String(), web.Bytes(buffer))
+            })
+        })
+    }
+
+    onKey := func(pressed bool) js.Func {
+        return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+            event := args[0]
+            if emulator == nil || event.Get("ctrlKey").Bool() || event.Get("metaKey").Bool() {
+                return nil
+            }
+            keyboard := emulator.Keyboard
+            handled := keyboard.Release
+            if pressed {
+                handled = keyboard.Press
+            }
+            if handled(event.Get("code").String()) {
+                event.Call("preventDefault")
+            }
+            return nil
+        })
+    }
+    js.Global().Call("addEventListener", "keydown", onKey(true))
+    js.Global().Call("addEventListener", "keyup", onKey(false))
+    js.Global().Call("addEventListener", "blur", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+        if emulator != nil {
+            emulator.Keyboard.Reset()
+        }
+        return nil
+    }))
+
+    var frame js.Func
+    frame = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+        animate(args[0].Float())
+        js.Global().Call("requestAnimationFrame", frame)
+        return nil
+    })
+    js.Global().Call("requestAnimationFrame", frame)
+
+    setStatus("open a rom to start")
+    // keep the callbacks alive
+    select {}
+}
+
+// load starts running a new program, replacing the current one
+func load(name string, program []byte) {
+    settings := romdb.Settings{
+        Quirks:     quirks.Get("value").String(),
+        Speed:      chip8.DefaultClockSpeed,
+        KeepQuirks: quirksChosen,
+    }
+    if entry, ok := database.Select(program, &settings); ok && entry.Title != "" {
+        name = entry.Title
+    }
+    profile, ok := chip8.QuirksProfile(settings.Quirks)
+    if !ok {
+        profile = chip8.QuirksModern
+    }
+    quirks.Set("value", settings.Quirks)
+    e, err := web.NewEmulator(program, profile, settings.Speed)
+    if err != nil {
+        setStatus(fmt.Sprintf("%s: %v", name, err))
+        return
+    }
+    emulator = e
+    if tone == nil {
+        // browsers only allow audio to start after a user action, such as
+        // picking a file
+        tone = newSpeaker()
+    }
+    setStatus(name)
+    // the page keeps the keyboard focus, not the file picker
+    document.Get("activeElement").Call("blur")
+}
+
+// animate runs the emulation up to the time of an animation frame and draws
+// the display
+func animate(now float64) {
+    if emulator == nil {
+        return
+    }
+    wasRunning := emulator.Err == nil
+    emulator.Animate(now)
+    if wasRunning && emulator.Err != nil {
+        if emulator.Exited() {
+            setStatus("exited")
+        } else {
+            setStatus(emulator.Err.Error())
+        }
+    }
+    if tone != nil {
+        tone.play(emulator.Sound())
+    }
+
+    pixels, width, height := emulator.Render()
+    if screen.Get("width").Int() != width || screen.Get("height").Int() != height {
+        screen.Set("width", width)
+        screen.Set("height", height)
+    }
+    imageData := context.Call("createImageData", width, height)
+    web.CopyPixels(imageData, pixels)
+    context.Call("putImageData", imageData, 0, 0)
+}
+
+func setStatus(text string) {
+    status.Set("textContent", text)
+}
+
+// then calls f with the result of a promise
+func then(promise js.Value, f func(js.Value)) {
+    var callback js.Func
+    callback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+        callback.Release()
+        f(args[0])
+        return nil
+    })
+    promise.Call("then", callback)
+}
+
+// speaker plays the tone with the Web Audio API, by turning the gain of a
+// square wave oscillator on and off
+type speaker struct {
+    gain    js.Value
+    playing bool
+}
+
+func newSpeaker() *speaker {
+    audioContext := js.Global().Get("AudioContext")
+    if audioContext.IsUndefined() {
+        return nil
+    }
+    ctx := audioContext.New()
+    oscillator := ctx.Call("createOscillator")
+    oscillator.Set("type", "square")
+    oscillator.Get("frequency").Set("value", audio.DefaultPitch)
+    gain := ctx.Call("createGain")
+    gain.Get("gain").Set("value", 0)
+    oscillator.Call("connect", gain)
+    gain.Call("connect", ctx.Get("destination"))
+    oscillator.Call("start")
+    return &speaker{gain: gain}
+}
+
+func (s *speaker) play(on bool) {
+    if s == nil || on == s.playing {
+        return
+    }
+    s.playing = on
+    volume := 0.0
+    if on {
+        volume = audio.DefaultVolume
+    }
+    s.gain.Get("gain").Set("value", volume)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+    "chip8-emulator/asm"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+func assembleCommand(args []string) int {
+    fs := flag.NewFlagSet("assemble", flag.ContinueOnError)
+    output := fs.String("o", "", "output file, defaults to the source file with a .ch8 extension")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8 assemble [flags] <source>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return 2
+    }
+    source := fs.Arg(0)
+    if *output == "" {
+        *output = strings.TrimSuffix(source, filepath.Ext(source)) + ".ch8"
+    }
+
+    data, err := ioutil.ReadFile(source)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    program, err := asm.Assemble(data)
+    if err != nil {
+        // prefix every line with the file name, like compilers do
+        for _, line := range strings.Split(err.Error(), "\n") {
+            fmt.Fprintf(os.Stderr, "%s: %s\n", source, line)
+        }
+        return 1
+    }
+    if err := ioutil.WriteFile(*output, program, 0644); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
+}
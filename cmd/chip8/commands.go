@@ -0,0 +1,24 @@
+package main
+
+import (
+    "sort"
+)
+
+// commands are the subcommands of the front end, which run without opening
+// a window. They return the exit code of the process.
+var commands = map[string]func(args []string) int{
+    "assemble": assembleCommand,
+    "debug":    debugCommand,
+    "disasm":   disasmCommand,
+    "identify": identifyCommand,
+}
+
+// commandNames returns the names of the subcommands, sorted
+func commandNames() []string {
+    names := make([]string, 0, len(commands))
+    for name := range commands {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
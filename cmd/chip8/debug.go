@@ -0,0 +1,41 @@
+package main
+
+import (
+    "chip8-emulator/debug"
+    "chip8-emulator/romdb"
+    "flag"
+    "fmt"
+    "os"
+)
+
+func debugCommand(args []string) int {
+    var opts machineOptions
+    fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8 debug [flags] <rom>")
+        fs.PrintDefaults()
+    }
+    opts.register(fs)
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+    if err := opts.parse(fs); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+
+    if err := opts.identify(&romdb.Settings{}); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    c, err := opts.load()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    if err := debug.NewREPL(debug.New(c), os.Stdin, os.Stdout).Run(); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
+}
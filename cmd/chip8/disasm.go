@@ -0,0 +1,45 @@
+package main
+
+import (
+    "chip8-emulator/asm"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+)
+
+func disasmCommand(args []string) int {
+    fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+    output := fs.String("o", "", "output file, defaults to standard output")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8 disasm [flags] <rom>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return 2
+    }
+
+    program, err := ioutil.ReadFile(fs.Arg(0))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    w := os.Stdout
+    if *output != "" {
+        if w, err = os.Create(*output); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        defer w.Close()
+    }
+    if err := asm.Disassemble(program).Format(w); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
+}
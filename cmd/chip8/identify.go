@@ -0,0 +1,68 @@
+package main
+
+import (
+    "chip8-emulator/romdb"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sort"
+)
+
+// identifyCommand prints the hash of a rom and its entry in the rom
+// database, to help adding roms to the database of the user
+func identifyCommand(args []string) int {
+    fs := flag.NewFlagSet("identify", flag.ContinueOnError)
+    dbPath := fs.String("romdb", "", "rom database to read on top of the bundled one and that of the user")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8 identify [flags] <rom>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return 2
+    }
+
+    program, err := ioutil.ReadFile(fs.Arg(0))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    var paths []string
+    if *dbPath != "" {
+        paths = append(paths, *dbPath)
+    }
+    db, err := romdb.Open(paths...)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    fmt.Printf("sha1:     %s\n", romdb.Hash(program))
+    e, ok := db.Lookup(program)
+    if !ok {
+        if user, err := romdb.UserPath(); err == nil {
+            fmt.Printf("not in the database, entries can be added to %s\n", user)
+        }
+        return 1
+    }
+    fmt.Printf("title:    %s\n", e.Title)
+    fmt.Printf("author:   %s\n", e.Author)
+    fmt.Printf("platform: %s\n", e.Platform)
+    fmt.Printf("quirks:   %s\n", e.QuirksProfile())
+    if e.Speed > 0 {
+        fmt.Printf("speed:    %d\n", e.Speed)
+    }
+    actions := make([]string, 0, len(e.Keys))
+    for action := range e.Keys {
+        actions = append(actions, action)
+    }
+    sort.Strings(actions)
+    for _, action := range actions {
+        fmt.Printf("key:      %s %X\n", action, e.Keys[action])
+    }
+    return 0
+}
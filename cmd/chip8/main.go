@@ -0,0 +1,181 @@
+package main
+
+import (
+    "chip8-emulator/audio"
+    "chip8-emulator/chip8"
+    "chip8-emulator/movie"
+    "chip8-emulator/rewind"
+    "errors"
+    "flag"
+    "fmt"
+    "github.com/faiface/pixel"
+    "github.com/faiface/pixel/pixelgl"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+func main() {
+    if len(os.Args) > 1 {
+        if command, ok := commands[os.Args[1]]; ok {
+            os.Exit(command(os.Args[2:]))
+        }
+    }
+
+    opts, err := parseOptions(os.Args[1:], os.Stderr)
+    if err == flag.ErrHelp {
+        return
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(2)
+    }
+
+    if err := opts.identify(); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    m, err := startMovie(opts)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    c, err := opts.load()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    pixelgl.Run(func() {
+        run(c, opts, m)
+    })
+}
+
+// run shows the emulation in a window until it is closed. If m is not nil it
+// is recorded or played back, depending on the options.
+func run(c *chip8.CPU, opts *options, m *movie.Movie) {
+    name := filepath.Base(opts.romPath)
+    if opts.entry != nil && opts.entry.Title != "" {
+        name = opts.entry.Title
+    }
+    cfg := pixelgl.WindowConfig{
+        Title:  "CHIP-8 - " + name,
+        Bounds: pixel.R(0, 0, float64(chip8.DisplayWidth * opts.scale), float64(chip8.DisplayHeight * opts.scale)),
+        VSync:  true,
+    }
+    win, err := pixelgl.NewWindow(cfg)
+    if err != nil {
+        panic(err)
+    }
+    c.Input = newWindowInput(win, opts.entry)
+    display := &windowDisplay{win: win, palette: opts.palette, scale: opts.scale}
+
+    // movies read the input once per frame. The user flags, save states and
+    // rewinding are not available, as they would change the outcome.
+    var recorder *movie.Recorder
+    var player *movie.Player
+    switch {
+    case m != nil && opts.playPath != "":
+        player = movie.NewPlayer(m)
+        c.Input = player
+    case m != nil:
+        recorder = movie.NewRecorder(c.Input, m)
+        c.Input = recorder
+        defer func() {
+            if err := writeMovie(m, opts.recordPath); err != nil {
+                fmt.Fprintf(os.Stderr, "movie: %v\n", err)
+            }
+        }()
+    default:
+        // the SUPER-CHIP user flags are kept next to the ROM
+        flags := chip8.FlagFile(opts.romPath + ".flags")
+        if c.Flags, err = flags.LoadFlags(); err != nil {
+            fmt.Fprintf(os.Stderr, "flags: %v\n", err)
+        }
+        c.FlagStore = flags
+    }
+
+    sound, err := newSound(opts)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "audio disabled: %v\n", err)
+        sound = audio.NewPlayer(audio.NewGenerator(audio.DefaultSampleRate), audio.Null{}, chip8.TimerFrequency)
+    }
+    defer sound.Close()
+
+    scheduler := chip8.NewScheduler(c, opts.clockSpeed)
+    ticker := time.NewTicker(time.Second / chip8.TimerFrequency)
+    defer ticker.Stop()
+
+    var history *rewind.History
+    if opts.rewindDepth > 0 && m == nil {
+        history = rewind.New(opts.rewindDepth)
+    }
+
+    title := cfg.Title
+    halted := false
+    for !win.Closed() {
+        <-ticker.C
+        if m == nil && handleStateKeys(win, c, opts.romPath) && halted {
+            halted = false
+            win.SetTitle(title)
+        }
+        // holding the rewind key steps back one frame at a time
+        rewinding := history != nil && win.Pressed(rewindKey)
+        if rewinding && history.Rewind(c) == nil && halted {
+            halted = false
+            win.SetTitle(title)
+        }
+        if !halted && !rewinding {
+            if history != nil {
+                if err := history.Save(c); err != nil {
+                    fmt.Fprintf(os.Stderr, "rewind: %v\n", err)
+                }
+            }
+            if recorder != nil {
+                recorder.Frame()
+            }
+            if player != nil {
+                if player.Done() {
+                    // hand control back to the keyboard
+                    fmt.Fprintf(os.Stderr, "movie finished after %d frames\n", player.Frames)
+                    c.Input = newWindowInput(win, opts.entry)
+                    player = nil
+                } else {
+                    player.Frame()
+                }
+            }
+            if err := scheduler.Frame(); errors.Is(err, chip8.ErrExit) {
+                win.SetClosed(true)
+            } else if err != nil {
+                // keep showing the last frame so the state at the time of
+                // the error can be inspected
+                halted = true
+                fmt.Fprintf(os.Stderr, "%s: %v\n", opts.romPath, err)
+                win.SetTitle("CHIP-8 - halted: " + err.Error())
+            }
+        }
+        if c.Quirks.XOChip {
+            sound.Generator.Pattern = c.AudioPattern[:]
+            sound.Generator.PatternRate = c.PatternRate()
+        }
+        if err := sound.Frame(!halted && !rewinding && c.SoundTimer > 0); err != nil {
+            fmt.Fprintf(os.Stderr, "audio: %v\n", err)
+        }
+        if err := display.Show(c.Frame()); err != nil {
+            fmt.Fprintf(os.Stderr, "display: %v\n", err)
+        }
+    }
+}
+
+// newSound returns a player for the configured audio backend
+func newSound(opts *options) (*audio.Player, error) {
+    backend, err := newAudioBackend(opts)
+    if err != nil {
+        return nil, err
+    }
+    generator := audio.NewGenerator(audio.DefaultSampleRate)
+    generator.Pitch = opts.pitch
+    generator.Volume = opts.volume
+    generator.Muted = opts.mute
+    return audio.NewPlayer(generator, backend, chip8.TimerFrequency), nil
+}
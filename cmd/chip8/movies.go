@@ -0,0 +1,60 @@
+package main
+
+import (
+    "chip8-emulator/chip8"
+    "chip8-emulator/movie"
+    "fmt"
+    "os"
+    "time"
+)
+
+// startMovie reads the movie to play back and applies its settings to the
+// options, or starts a new movie to record. It is called after identify read
+// the rom, and returns nil if neither was requested.
+func startMovie(opts *options) (*movie.Movie, error) {
+    if opts.playPath == "" && opts.recordPath == "" {
+        return nil, nil
+    }
+    if opts.playPath != "" {
+        m, err := movie.ReadFile(opts.playPath)
+        if err != nil {
+            return nil, err
+        }
+        if err := m.Check(opts.program); err != nil {
+            return nil, fmt.Errorf("%s: %w", opts.playPath, err)
+        }
+        quirks, ok := chip8.QuirksProfile(m.Quirks)
+        if !ok {
+            return nil, fmt.Errorf("%s: unknown quirk profile: %q", opts.playPath, m.Quirks)
+        }
+        opts.quirksName = m.Quirks
+        opts.quirks = quirks
+        opts.seed = m.Seed
+        opts.clockSpeed = m.ClockSpeed
+        return m, nil
+    }
+
+    // the seed must be known to replay the movie
+    if opts.seed == 0 {
+        opts.seed = time.Now().UnixNano()
+    }
+    return &movie.Movie{
+        ROM:        movie.Hash(opts.program),
+        Quirks:     opts.quirksName,
+        Seed:       opts.seed,
+        ClockSpeed: opts.clockSpeed,
+    }, nil
+}
+
+
+func writeMovie(m *movie.Movie, path string) error {
+    f, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    if err := m.Write(f); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+    "chip8-emulator/audio"
+    "chip8-emulator/chip8"
+    "chip8-emulator/rewind"
+    "chip8-emulator/romdb"
+    "flag"
+    "fmt"
+    "golang.org/x/image/colornames"
+    "image/color"
+    "io"
+    "io/ioutil"
+    "strconv"
+    "strings"
+)
+
+// machineOptions holds the settings shared by all commands that run a rom
+type machineOptions struct {
+    romPath    string
+    // program is the rom, read by identify
+    program    []byte
+    quirksName string
+    quirks     chip8.Quirks
+    // seed for the random number generator, 0 seeds from the current time
+    seed       int64
+    // romdbPath is a rom database read on top of the bundled one and that
+    // of the user
+    romdbPath  string
+    // entry describes the rom, if it is in the database
+    entry      *romdb.Entry
+    // set holds the names of the flags given on the command line, which
+    // take precedence over the database
+    set        map[string]bool
+}
+
+// register adds the flags for the machine options to fs
+func (m *machineOptions) register(fs *flag.FlagSet) {
+    fs.StringVar(&m.quirksName, "quirks", "modern", "quirk profile, one of: " + strings.Join(chip8.QuirksProfiles(), ", "))
+    fs.Int64Var(&m.seed, "seed", 0, "seed for the random number generator, 0 for a random seed")
+    fs.StringVar(&m.romdbPath, "romdb", "", "rom database to read on top of the bundled one and that of the user")
+}
+
+// parse validates the flags after fs was parsed, and takes the rom from the
+// remaining arguments
+func (m *machineOptions) parse(fs *flag.FlagSet) error {
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return fmt.Errorf("expected exactly one rom, got %d arguments", fs.NArg())
+    }
+    m.romPath = fs.Arg(0)
+    m.set = map[string]bool{}
+    fs.Visit(func(f *flag.Flag) {
+        m.set[f.Name] = true
+    })
+
+    var ok bool
+    if m.quirks, ok = chip8.QuirksProfile(m.quirksName); !ok {
+        return fmt.Errorf("unknown quirk profile: %q", m.quirksName)
+    }
+    return nil
+}
+
+// identify reads the rom and looks it up in the rom database. The quirks of
+// its entry are used unless they were set on the command line, and the clock
+// speed in s is replaced in the same way.
+func (m *machineOptions) identify(s *romdb.Settings) error {
+    p, err := ioutil.ReadFile(m.romPath)
+    if err != nil {
+        return err
+    }
+    m.program = p
+    var paths []string
+    if m.romdbPath != "" {
+        paths = append(paths, m.romdbPath)
+    }
+    db, err := romdb.Open(paths...)
+    if err != nil {
+        return err
+    }
+    s.Quirks = m.quirksName
+    s.KeepQuirks = m.set["quirks"]
+    e, ok := db.Select(p, s)
+    if !ok {
+        return nil
+    }
+    m.entry = &e
+    m.quirksName = s.Quirks
+    m.quirks, _ = chip8.QuirksProfile(s.Quirks)
+    return nil
+}
+
+// load returns a CPU that runs the rom read by identify with the options
+func (m *machineOptions) load() (*chip8.CPU, error) {
+    c, err := chip8.NewCPUWithQuirks(m.program, m.quirks)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", m.romPath, err)
+    }
+    if m.seed != 0 {
+        c.Seed(m.seed)
+    }
+    return c, nil
+}
+
+// options holds the settings of the front end, as set on the command line
+type options struct {
+    machineOptions
+    clockSpeed int
+    scale      int
+    // palette is the default palette with the colors set on the command
+    // line
+    palette    chip8.Palette
+    pitch      float64
+    volume     float64
+    mute       bool
+    // wavPath is the file the sound is written to instead of the speaker
+    wavPath    string
+    // rewindDepth is the number of frames that can be rewound, 0 disables
+    // rewinding
+    rewindDepth int
+    // recordPath and playPath are the movie files to record to or play
+    // back
+    recordPath string
+    playPath   string
+}
+
+// identify reads the rom and looks it up in the rom database, and uses the
+// quirks and clock speed of its entry unless they were set on the command
+// line
+func (opts *options) identify() error {
+    s := romdb.Settings{Speed: opts.clockSpeed, KeepSpeed: opts.set["speed"]}
+    if err := opts.machineOptions.identify(&s); err != nil {
+        return err
+    }
+    opts.clockSpeed = s.Speed
+    return nil
+}
+
+// parseOptions parses the command line arguments, excluding the program name
+func parseOptions(args []string, output io.Writer) (*options, error) {
+    opts := &options{}
+    fs := flag.NewFlagSet("chip8", flag.ContinueOnError)
+    fs.SetOutput(output)
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: chip8 [flags] <rom>")
+        fmt.Fprintln(fs.Output(), "       chip8 <command> [flags] <file>")
+        fmt.Fprintln(fs.Output(), "commands: " + strings.Join(commandNames(), ", "))
+        fs.PrintDefaults()
+    }
+
+    fg := fs.String("fg", "", "foreground color, as a name or #RRGGBB, instead of the white of the default palette")
+    bg := fs.String("bg", "", "background color, as a name or #RRGGBB, instead of the black of the default palette")
+    palette := fs.String("palette", "", "4 comma separated colors for unlit pixels, the first and second XO-CHIP plane and both planes, instead of the default palette and -fg and -bg")
+    fs.IntVar(&opts.clockSpeed, "speed", chip8.DefaultClockSpeed, "clock speed in instructions per second")
+    fs.IntVar(&opts.scale, "scale", 10, "size of a low resolution pixel on screen, high resolution pixels are half as big")
+    fs.Float64Var(&opts.pitch, "pitch", audio.DefaultPitch, "pitch of the tone in Hz")
+    fs.Float64Var(&opts.volume, "volume", audio.DefaultVolume, "volume of the tone, between 0 and 1")
+    fs.BoolVar(&opts.mute, "mute", false, "disable sound")
+    fs.StringVar(&opts.wavPath, "wav", "", "write sound to a WAV file instead of the speaker")
+    fs.IntVar(&opts.rewindDepth, "rewind", rewind.DefaultDepth, "number of frames that can be rewound by holding Backspace, 0 to disable")
+    fs.StringVar(&opts.recordPath, "record", "", "record the input to a movie file")
+    fs.StringVar(&opts.playPath, "play", "", "play back a movie file, with the settings it was recorded with")
+    opts.machineOptions.register(fs)
+
+    if err := fs.Parse(args); err != nil {
+        return nil, err
+    }
+    if err := opts.machineOptions.parse(fs); err != nil {
+        return nil, err
+    }
+
+    if opts.clockSpeed <= 0 {
+        return nil, fmt.Errorf("invalid clock speed: %d", opts.clockSpeed)
+    }
+    if opts.scale <= 0 {
+        return nil, fmt.Errorf("invalid scale: %d", opts.scale)
+    }
+    if opts.pitch <= 0 {
+        return nil, fmt.Errorf("invalid pitch: %v", opts.pitch)
+    }
+    if opts.volume < 0 || opts.volume > 1 {
+        return nil, fmt.Errorf("invalid volume: %v", opts.volume)
+    }
+    if opts.rewindDepth < 0 {
+        return nil, fmt.Errorf("invalid rewind depth: %d", opts.rewindDepth)
+    }
+    if opts.recordPath != "" && opts.playPath != "" {
+        return nil, fmt.Errorf("can not record and play a movie at the same time")
+    }
+
+    // the colors that are set replace those of the default palette
+    colors := []string{*bg, *fg, "", ""}
+    if *palette != "" {
+        colors = strings.Split(*palette, ",")
+        if len(colors) != len(opts.palette) {
+            return nil, fmt.Errorf("invalid palette: expected %d colors, got %d", len(opts.palette), len(colors))
+        }
+    }
+    opts.palette = chip8.DefaultPalette
+    for i, value := range colors {
+        if value == "" {
+            continue
+        }
+        var err error
+        if opts.palette[i], err = parseColor(strings.TrimSpace(value)); err != nil {
+            return nil, err
+        }
+    }
+    return opts, nil
+}
+
+// parseColor accepts a color name such as "green" or a hexadecimal #RRGGBB
+// value
+func parseColor(value string) (color.RGBA, error) {
+    if c, ok := colornames.Map[strings.ToLower(value)]; ok {
+        return c, nil
+    }
+    hex := strings.TrimPrefix(value, "#")
+    if len(hex) != 6 {
+        return color.RGBA{}, fmt.Errorf("invalid color: %q", value)
+    }
+    rgb, err := strconv.ParseUint(hex, 16, 32)
+    if err != nil {
+        return color.RGBA{}, fmt.Errorf("invalid color: %q", value)
+    }
+    return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, nil
+}
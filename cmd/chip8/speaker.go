@@ -0,0 +1,58 @@
+package main
+
+import (
+    "chip8-emulator/audio"
+    "encoding/binary"
+    "github.com/hajimehoshi/oto"
+)
+
+// speaker is an audio backend that plays samples on the default sound device
+type speaker struct {
+    context *oto.Context
+    player  *oto.Player
+    buffer  []byte
+}
+
+func newSpeaker(sampleRate int) (*speaker, error) {
+    // buffer a few frames, so the tone does not stutter when a frame is late
+    bufferSize := sampleRate / 60 * 2 * 4
+    context, err := oto.NewContext(sampleRate, 1, 2, bufferSize)
+    if err != nil {
+        return nil, err
+    }
+    return &speaker{
+        context: context,
+        player:  context.NewPlayer(),
+    }, nil
+}
+
+func (s *speaker) WriteSamples(samples []int16) error {
+    if cap(s.buffer) < len(samples) * 2 {
+        s.buffer = make([]byte, len(samples) * 2)
+    }
+    s.buffer = s.buffer[:len(samples) * 2]
+    for i, sample := range samples {
+        binary.LittleEndian.PutUint16(s.buffer[i * 2:], uint16(sample))
+    }
+    _, err := s.player.Write(s.buffer)
+    return err
+}
+
+func (s *speaker) Close() error {
+    if err := s.player.Close(); err != nil {
+        return err
+    }
+    return s.context.Close()
+}
+
+// newAudioBackend returns a WAV backend if a path is given, and the speaker
+// otherwise. The speaker is not opened at all when muted.
+func newAudioBackend(opts *options) (audio.Backend, error) {
+    if opts.wavPath != "" {
+        return audio.CreateWAV(opts.wavPath, audio.DefaultSampleRate)
+    }
+    if opts.mute {
+        return audio.Null{}, nil
+    }
+    return newSpeaker(audio.DefaultSampleRate)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "fmt"
+    "github.com/faiface/pixel/pixelgl"
+    "io/ioutil"
+    "os"
+)
+
+// stateKeys select the save slots 1 to 9. Shift and a function key saves the
+// machine state to its slot, the function key alone loads it.
+var stateKeys = [...]pixelgl.Button{
+    pixelgl.KeyF1,
+    pixelgl.KeyF2,
+    pixelgl.KeyF3,
+    pixelgl.KeyF4,
+    pixelgl.KeyF5,
+    pixelgl.KeyF6,
+    pixelgl.KeyF7,
+    pixelgl.KeyF8,
+    pixelgl.KeyF9,
+}
+
+// statePath returns the file of a save slot, which is stored next to the rom
+func statePath(romPath string, slot int) string {
+    return fmt.Sprintf("%s.%d.state", romPath, slot)
+}
+
+func saveState(c *chip8.CPU, path string) error {
+    data, err := c.MarshalBinary()
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(path, data, 0644)
+}
+
+func loadState(c *chip8.CPU, path string) error {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    return c.ReadState(bytes.NewReader(data))
+}
+
+// handleStateKeys saves or loads the machine state when one of the state keys
+// was pressed. It returns true if a state was loaded.
+func handleStateKeys(win *pixelgl.Window, c *chip8.CPU, romPath string) bool {
+    save := win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift)
+    for i, key := range stateKeys {
+        if !win.JustPressed(key) {
+            continue
+        }
+        slot := i + 1
+        path := statePath(romPath, slot)
+        if save {
+            if err := saveState(c, path); err != nil {
+                fmt.Fprintf(os.Stderr, "saving slot %d: %v\n", slot, err)
+                return false
+            }
+            fmt.Fprintf(os.Stderr, "saved slot %d to %s\n", slot, path)
+            return false
+        }
+        if err := loadState(c, path); err != nil {
+            fmt.Fprintf(os.Stderr, "loading slot %d: %v\n", slot, err)
+            return false
+        }
+        fmt.Fprintf(os.Stderr, "loaded slot %d from %s\n", slot, path)
+        return true
+    }
+    return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+    "chip8-emulator/chip8"
+    "chip8-emulator/romdb"
+    "github.com/faiface/pixel"
+    "github.com/faiface/pixel/pixelgl"
+    "image/color"
+)
+
+// keyMap maps the hexadecimal keypad onto the left side of a keyboard:
+//
+//   1 2 3 C      1 2 3 4
+//   4 5 6 D  ->  Q W E R
+//   7 8 9 E      A S D F
+//   A 0 B F      Z X C V
+var keyMap = [chip8.KeyCount]pixelgl.Button{
+    pixelgl.KeyX, // 0
+    pixelgl.Key1, // 1
+    pixelgl.Key2, // 2
+    pixelgl.Key3, // 3
+    pixelgl.KeyQ, // 4
+    pixelgl.KeyW, // 5
+    pixelgl.KeyE, // 6
+    pixelgl.KeyA, // 7
+    pixelgl.KeyS, // 8
+    pixelgl.KeyD, // 9
+    pixelgl.KeyZ, // A
+    pixelgl.KeyC, // B
+    pixelgl.Key4, // C
+    pixelgl.KeyR, // D
+    pixelgl.KeyF, // E
+    pixelgl.KeyV, // F
+}
+
+// rewindKey steps the emulation backwards while it is held
+const rewindKey = pixelgl.KeyBackspace
+
+// actionButtons maps the actions of the rom database onto the arrow keys,
+// space and enter
+var actionButtons = map[string]pixelgl.Button{
+    "up":    pixelgl.KeyUp,
+    "down":  pixelgl.KeyDown,
+    "left":  pixelgl.KeyLeft,
+    "right": pixelgl.KeyRight,
+    "a":     pixelgl.KeySpace,
+    "b":     pixelgl.KeyEnter,
+}
+
+// windowInput reads the keypad state from the keyboard of a pixelgl window
+type windowInput struct {
+    win     *pixelgl.Window
+    // actions maps buttons onto keys in addition to keyMap, following the
+    // key mapping of the rom database
+    actions map[pixelgl.Button]int
+}
+
+// newWindowInput returns the input of a window, with the key mapping of
+// the entry if it is not nil
+func newWindowInput(win *pixelgl.Window, entry *romdb.Entry) *windowInput {
+    i := &windowInput{win: win, actions: map[pixelgl.Button]int{}}
+    if entry != nil {
+        for action, key := range entry.Keys {
+            i.actions[actionButtons[action]] = key
+        }
+    }
+    return i
+}
+
+func (i *windowInput) KeyState() [chip8.KeyCount]bool {
+    var keys [chip8.KeyCount]bool
+    for k, button := range keyMap {
+        keys[k] = i.win.Pressed(button)
+    }
+    for button, k := range i.actions {
+        keys[k] = keys[k] || i.win.Pressed(button)
+    }
+    return keys
+}
+
+// windowDisplay draws the frames of the emulation to a pixelgl window. The
+// window is sized for the low resolution display and keeps its size when a
+// SUPER-CHIP program switches to high resolution, which is then drawn with
+// pixels of half the size, so both fill the window.
+type windowDisplay struct {
+    win     *pixelgl.Window
+    palette chip8.Palette
+    // scale is the size of a low resolution pixel on screen
+    scale   int
+}
+
+// Show draws the frame over the whole window and updates it, which also
+// polls the keyboard
+func (d *windowDisplay) Show(f *chip8.Frame) error {
+    var colors [len(d.palette)]color.RGBA
+    for i, c := range d.palette {
+        colors[i] = color.RGBAModel.Convert(c).(color.RGBA)
+    }
+    d.win.Clear(colors[0])
+    screen := pixel.MakePictureData(pixel.R(0, 0, float64(f.Width), float64(f.Height)))
+    for x := 0; x < f.Width; x++ {
+        for y := 0; y < f.Height; y++ {
+            // pictures have their origin at the bottom left
+            i := x + (f.Height - 1 - y) * f.Width
+            screen.Pix[i] = colors[int(f.At(x, y)) % len(colors)]
+        }
+    }
+
+    // the window keeps its size, high resolution pixels are half as big
+    scale := float64(d.scale * chip8.DisplayWidth) / float64(f.Width)
+    sprite := pixel.NewSprite(screen, screen.Bounds())
+    sprite.Draw(d.win, pixel.IM.Scaled(pixel.ZV, scale).Moved(d.win.Bounds().Center()))
+    d.win.Update()
+    return nil
+}
@@ -0,0 +1,267 @@
+// Package debug implements a step debugger for the CHIP-8 CPU, with
+// breakpoints, watchpoints and a terminal REPL to drive it.
+package debug
+
+import (
+    "chip8-emulator/chip8"
+    "fmt"
+    "sort"
+)
+
+// Reason describes why execution stopped
+type Reason int
+
+const (
+    // Stepped means the requested number of instructions was executed
+    Stepped Reason = iota
+    // Breakpoint means the program counter reached a breakpoint
+    Breakpoint
+    // Watchpoint means a watched value changed
+    Watchpoint
+    // Returned means the subroutine being stepped over or out of returned
+    Returned
+    // Halted means an instruction returned an error
+    Halted
+    // Limit means the maximum number of instructions was executed without
+    // any other reason to stop
+    Limit
+)
+
+func (r Reason) String() string {
+    switch r {
+    case Stepped:
+        return "stepped"
+    case Breakpoint:
+        return "breakpoint"
+    case Watchpoint:
+        return "watchpoint"
+    case Returned:
+        return "returned"
+    case Halted:
+        return "halted"
+    case Limit:
+        return "limit"
+    }
+    return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// Stop describes where and why execution stopped
+type Stop struct {
+    Reason Reason
+    // Watch is the watchpoint that triggered, for Watchpoint
+    Watch *Watch
+    // Old and New are the values of the watchpoint, for Watchpoint
+    Old, New int
+    // Err is the error returned by the CPU, for Halted
+    Err error
+}
+
+// Watch is a watchpoint on a register or memory address
+type Watch struct {
+    Name  string
+    read  func(c *chip8.CPU) int
+    value int
+}
+
+// DefaultLimit is the number of instructions Continue, StepOver and StepOut
+// execute at most, so a program waiting for a key can be interrupted
+const DefaultLimit = 1000000
+
+// Debugger controls the execution of a CPU
+type Debugger struct {
+    CPU *chip8.CPU
+    // CyclesPerTick is the number of instructions executed between two ticks
+    // of the timers, so timers behave as they would at full speed
+    CyclesPerTick int
+    // Limit is the number of instructions Continue, StepOver and StepOut
+    // execute at most
+    Limit int
+
+    breakpoints map[uint16]bool
+    watches     []*Watch
+    cycles      int
+}
+
+func New(cpu *chip8.CPU) *Debugger {
+    return &Debugger{
+        CPU:           cpu,
+        CyclesPerTick: chip8.DefaultClockSpeed / chip8.TimerFrequency,
+        Limit:         DefaultLimit,
+        breakpoints:   map[uint16]bool{},
+    }
+}
+
+// AddBreakpoint stops execution before the instruction at addr
+func (d *Debugger) AddBreakpoint(addr uint16) {
+    d.breakpoints[addr] = true
+}
+
+// RemoveBreakpoint removes the breakpoint at addr, and returns false if there
+// was none
+func (d *Debugger) RemoveBreakpoint(addr uint16) bool {
+    if !d.breakpoints[addr] {
+        return false
+    }
+    delete(d.breakpoints, addr)
+    return true
+}
+
+// Breakpoints returns the addresses of all breakpoints, sorted
+func (d *Debugger) Breakpoints() []uint16 {
+    addrs := make([]uint16, 0, len(d.breakpoints))
+    for addr := range d.breakpoints {
+        addrs = append(addrs, addr)
+    }
+    sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+    return addrs
+}
+
+// WatchMemory stops execution when the byte at addr changes
+func (d *Debugger) WatchMemory(addr uint16) *Watch {
+    return d.watch(fmt.Sprintf("[%03X]", addr), func(c *chip8.CPU) int {
+        return int(c.Memory[addr])
+    })
+}
+
+// WatchRegister stops execution when Vx changes
+func (d *Debugger) WatchRegister(x int) *Watch {
+    return d.watch(fmt.Sprintf("V%X", x), func(c *chip8.CPU) int {
+        return int(c.Register[x])
+    })
+}
+
+// WatchIndex stops execution when I changes
+func (d *Debugger) WatchIndex() *Watch {
+    return d.watch("I", func(c *chip8.CPU) int {
+        return int(c.Index)
+    })
+}
+
+func (d *Debugger) watch(name string, read func(c *chip8.CPU) int) *Watch {
+    for _, w := range d.watches {
+        if w.Name == name {
+            return w
+        }
+    }
+    w := &Watch{Name: name, read: read, value: read(d.CPU)}
+    d.watches = append(d.watches, w)
+    return w
+}
+
+// Unwatch removes the watchpoint with the given name, and returns false if
+// there was none
+func (d *Debugger) Unwatch(name string) bool {
+    for i, w := range d.watches {
+        if w.Name == name {
+            d.watches = append(d.watches[:i], d.watches[i + 1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+// Watches returns all watchpoints
+func (d *Debugger) Watches() []*Watch {
+    return d.watches
+}
+
+// cycle executes a single instruction, ticking the timers as needed, and
+// reports a stop for errors and changed watchpoints
+func (d *Debugger) cycle() *Stop {
+    if err := d.CPU.Cycle(); err != nil {
+        return &Stop{Reason: Halted, Err: err}
+    }
+    d.cycles++
+    if d.CyclesPerTick > 0 && d.cycles % d.CyclesPerTick == 0 {
+        d.CPU.TickTimers()
+    }
+    // every watch is updated, so the values changed along with the one
+    // reported are not reported again by the next instruction
+    var stop *Stop
+    for _, w := range d.watches {
+        if v := w.read(d.CPU); v != w.value {
+            if stop == nil {
+                stop = &Stop{Reason: Watchpoint, Watch: w, Old: w.value, New: v}
+            }
+            w.value = v
+        }
+    }
+    return stop
+}
+
+// Step executes n instructions, stopping early at errors, breakpoints and
+// watchpoints. The breakpoint at the current instruction is ignored, so
+// execution can continue from a breakpoint.
+func (d *Debugger) Step(n int) Stop {
+    return d.run(n, Stepped, func() bool { return false })
+}
+
+// Continue executes instructions until a breakpoint, watchpoint or error, or
+// until Limit instructions were executed
+func (d *Debugger) Continue() Stop {
+    return d.run(d.Limit, Limit, func() bool { return false })
+}
+
+// OpCode returns the instruction at the program counter, and false if the
+// program counter is out of bounds
+func (d *Debugger) OpCode() (uint16, bool) {
+    pc := int(d.CPU.ProgramCounter)
+    if pc + 1 >= len(d.CPU.Memory) {
+        return 0, false
+    }
+    return uint16(d.CPU.Memory[pc]) << 8 | uint16(d.CPU.Memory[pc + 1]), true
+}
+
+// StepOver executes a single instruction, but runs a CALL until the
+// subroutine returns
+func (d *Debugger) StepOver() Stop {
+    opCode, ok := d.OpCode()
+    if !ok || chip8.Decode(opCode).Op != chip8.OpCALL {
+        return d.Step(1)
+    }
+    ret := d.CPU.ProgramCounter + 2
+    sp := d.CPU.StackPointer
+    return d.runUntil(func() bool {
+        return d.CPU.ProgramCounter == ret && d.CPU.StackPointer == sp
+    })
+}
+
+// StepOut executes instructions until the current subroutine returns
+func (d *Debugger) StepOut() Stop {
+    sp := d.CPU.StackPointer
+    if sp == 0 {
+        return d.Continue()
+    }
+    return d.runUntil(func() bool {
+        return d.CPU.StackPointer < sp
+    })
+}
+
+func (d *Debugger) runUntil(done func() bool) Stop {
+    stop := d.run(d.Limit, Limit, done)
+    if stop.Reason == Stepped {
+        stop.Reason = Returned
+    }
+    return stop
+}
+
+// run executes at most n instructions, until done returns true or another
+// reason to stop occurs. If all n instructions were executed, the stop has
+// the given reason.
+func (d *Debugger) run(n int, reason Reason, done func() bool) Stop {
+    for i := 0; i < n; i++ {
+        if i > 0 && d.breakpoints[d.CPU.ProgramCounter] {
+            return Stop{Reason: Breakpoint}
+        }
+        if stop := d.cycle(); stop != nil {
+            return *stop
+        }
+        if done() {
+            return Stop{Reason: Stepped}
+        }
+    }
+    if reason == Stepped || !d.breakpoints[d.CPU.ProgramCounter] {
+        return Stop{Reason: reason}
+    }
+    return Stop{Reason: Breakpoint}
+}
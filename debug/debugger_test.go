@@ -0,0 +1,186 @@
+package debug
+
+import (
+    "chip8-emulator/chip8"
+    "errors"
+    "testing"
+)
+
+func newDebugger(t *testing.T, ops ...uint16) *Debugger {
+    t.Helper()
+    c, err := chip8.NewCPU(chip8.Build(ops...))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return New(c)
+}
+
+func Test_Step(t *testing.T) {
+    d := newDebugger(t,
+        chip8.LD(0x1, 0x1),
+        chip8.LD(0x2, 0x2),
+        chip8.LD(0x3, 0x3),
+    )
+
+    stop := d.Step(2)
+
+    if stop.Reason != Stepped {
+        t.Errorf("unexpected stop: %v", stop.Reason)
+    }
+    if d.CPU.ProgramCounter != 0x204 {
+        t.Errorf("unexpected pc: %x", d.CPU.ProgramCounter)
+    }
+}
+
+func Test_Continue_breakpoint(t *testing.T) {
+    d := newDebugger(t,
+        chip8.ADD(0x1, 0x1),
+        chip8.ADD(0x2, 0x1),
+        chip8.JP(0x200),
+    )
+    d.AddBreakpoint(0x202)
+
+    stop := d.Continue()
+
+    if stop.Reason != Breakpoint || d.CPU.ProgramCounter != 0x202 {
+        t.Errorf("unexpected stop: %v at %x", stop.Reason, d.CPU.ProgramCounter)
+    }
+
+    stop = d.Continue()
+
+    if stop.Reason != Breakpoint || d.CPU.Register[0x1] != 2 {
+        t.Errorf("should continue from a breakpoint: %v, v1 %v", stop.Reason, d.CPU.Register[0x1])
+    }
+
+    if !d.RemoveBreakpoint(0x202) || d.RemoveBreakpoint(0x202) {
+        t.Error("breakpoint should be removed once")
+    }
+    d.Limit = 30
+    if stop = d.Continue(); stop.Reason != Limit {
+        t.Errorf("unexpected stop: %v", stop.Reason)
+    }
+}
+
+func Test_Continue_watchpoints(t *testing.T) {
+    d := newDebugger(t,
+        chip8.LD(0x1, 0x0),
+        chip8.LD(0x2, 0x7),
+        chip8.LDI(0x300),
+        chip8.LD_I_VX(0x2),
+        chip8.JP(0x208),
+    )
+    register := d.WatchRegister(0x2)
+    memory := d.WatchMemory(0x302)
+    index := d.WatchIndex()
+
+    stop := d.Continue()
+
+    if stop.Reason != Watchpoint || stop.Watch != register || stop.Old != 0 || stop.New != 7 {
+        t.Errorf("unexpected stop: %+v", stop)
+    }
+    if stop = d.Continue(); stop.Watch != index || stop.New != 0x300 {
+        t.Errorf("unexpected stop: %+v", stop)
+    }
+    if stop = d.Continue(); stop.Watch != memory || stop.New != 7 {
+        t.Errorf("unexpected stop: %+v", stop)
+    }
+    if !d.Unwatch("V2") || len(d.Watches()) != 2 {
+        t.Error("watchpoint should be removed")
+    }
+}
+
+func Test_Continue_watchpoints_same_instruction(t *testing.T) {
+    d := newDebugger(t,
+        chip8.LDI(0x300),
+        chip8.LD_VX_I(0x1),
+        chip8.LD(0x3, 0x1),
+        chip8.JP(0x206),
+    )
+    d.CPU.Memory[0x300] = 1
+    d.CPU.Memory[0x301] = 2
+    first := d.WatchRegister(0x0)
+    second := d.WatchRegister(0x1)
+
+    stop := d.Continue()
+
+    if stop.Reason != Watchpoint || stop.Watch != first || d.CPU.ProgramCounter != 0x204 {
+        t.Errorf("unexpected stop: %+v at %x", stop, d.CPU.ProgramCounter)
+    }
+    if second.value != 2 {
+        t.Errorf("the other changed watchpoint should be updated: %v", second.value)
+    }
+    if stop = d.Step(1); stop.Reason != Stepped {
+        t.Errorf("unexpected stop: %+v at %x", stop, d.CPU.ProgramCounter)
+    }
+}
+
+func Test_StepOver(t *testing.T) {
+    d := newDebugger(t,
+        chip8.CALL(0x206),
+        chip8.LD(0x2, 0x2),
+        chip8.JP(0x204),
+        chip8.ADD(0x1, 0x1),
+        chip8.RET(),
+    )
+
+    stop := d.StepOver()
+
+    if stop.Reason != Returned || d.CPU.ProgramCounter != 0x202 {
+        t.Errorf("unexpected stop: %v at %x", stop.Reason, d.CPU.ProgramCounter)
+    }
+    if d.CPU.Register[0x1] != 1 {
+        t.Error("subroutine should be executed")
+    }
+
+    stop = d.StepOver()
+
+    if stop.Reason != Stepped || d.CPU.ProgramCounter != 0x204 {
+        t.Errorf("unexpected stop: %v at %x", stop.Reason, d.CPU.ProgramCounter)
+    }
+}
+
+func Test_StepOut(t *testing.T) {
+    d := newDebugger(t,
+        chip8.CALL(0x204),
+        chip8.JP(0x202),
+        chip8.ADD(0x1, 0x1),
+        chip8.ADD(0x1, 0x1),
+        chip8.RET(),
+    )
+    d.Step(2)
+
+    stop := d.StepOut()
+
+    if stop.Reason != Returned || d.CPU.ProgramCounter != 0x202 {
+        t.Errorf("unexpected stop: %v at %x", stop.Reason, d.CPU.ProgramCounter)
+    }
+    if d.CPU.Register[0x1] != 2 {
+        t.Errorf("unexpected v1: %v", d.CPU.Register[0x1])
+    }
+}
+
+func Test_Halted(t *testing.T) {
+    d := newDebugger(t,
+        chip8.RET(),
+    )
+
+    stop := d.Continue()
+
+    if stop.Reason != Halted || !errors.Is(stop.Err, chip8.ErrStackUnderflow) {
+        t.Errorf("unexpected stop: %+v", stop)
+    }
+}
+
+func Test_Timers(t *testing.T) {
+    d := newDebugger(t,
+        chip8.JP(0x200),
+    )
+    d.CPU.DelayTimer = 10
+    d.CyclesPerTick = 4
+
+    d.Step(8)
+
+    if d.CPU.DelayTimer != 8 {
+        t.Errorf("unexpected delay timer: %v", d.CPU.DelayTimer)
+    }
+}
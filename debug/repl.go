@@ -0,0 +1,258 @@
+package debug
+
+import (
+    "bufio"
+    "chip8-emulator/chip8"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+)
+
+const help = `commands:
+  s, step [n]           execute n instructions, 1 by default
+  n, next               execute an instruction, running a CALL until it returns
+  f, finish             run until the current subroutine returns
+  c, continue           run until a breakpoint, watchpoint or error
+  b, break <addr>       add a breakpoint
+  d, delete <addr>      remove a breakpoint
+  w, watch <target>     watch a register (v0-vf, i) or memory address
+  u, unwatch <target>   remove a watchpoint
+  l, list               list breakpoints and watchpoints
+  r, regs               show the registers, stack and timers
+  x <addr> [n]          show n bytes of memory, 16 by default
+  press <key>           hold down a key of the keypad
+  release <key>         release a key of the keypad
+  h, help               show this help
+  q, quit               exit the debugger
+`
+
+// REPL is a line based interface to a debugger, which can be driven from a
+// terminal or a script
+type REPL struct {
+    Debugger *Debugger
+    In       io.Reader
+    Out      io.Writer
+}
+
+func NewREPL(d *Debugger, in io.Reader, out io.Writer) *REPL {
+    return &REPL{Debugger: d, In: in, Out: out}
+}
+
+// Run reads and executes commands until the input ends or quit is entered
+func (r *REPL) Run() error {
+    r.printState()
+    scanner := bufio.NewScanner(r.In)
+    for {
+        fmt.Fprint(r.Out, "(chip8) ")
+        if !scanner.Scan() {
+            fmt.Fprintln(r.Out)
+            return scanner.Err()
+        }
+        fields := strings.Fields(scanner.Text())
+        if len(fields) == 0 {
+            continue
+        }
+        if fields[0] == "q" || fields[0] == "quit" {
+            return nil
+        }
+        if err := r.execute(fields[0], fields[1:]); err != nil {
+            fmt.Fprintf(r.Out, "error: %v\n", err)
+        }
+    }
+}
+
+func (r *REPL) execute(command string, args []string) error {
+    d := r.Debugger
+    switch command {
+    case "s", "step":
+        n := 1
+        if len(args) > 0 {
+            v, err := strconv.Atoi(args[0])
+            if err != nil || v < 1 {
+                return fmt.Errorf("invalid count: %q", args[0])
+            }
+            n = v
+        }
+        r.printStop(d.Step(n))
+    case "n", "next":
+        r.printStop(d.StepOver())
+    case "f", "finish":
+        r.printStop(d.StepOut())
+    case "c", "continue":
+        r.printStop(d.Continue())
+    case "b", "break", "d", "delete":
+        if len(args) != 1 {
+            return fmt.Errorf("expected an address")
+        }
+        addr, err := parseAddress(args[0])
+        if err != nil {
+            return err
+        }
+        if command == "b" || command == "break" {
+            d.AddBreakpoint(addr)
+        } else if !d.RemoveBreakpoint(addr) {
+            return fmt.Errorf("no breakpoint at %03X", addr)
+        }
+    case "w", "watch":
+        if len(args) != 1 {
+            return fmt.Errorf("expected a register or address")
+        }
+        w, err := r.watch(args[0])
+        if err != nil {
+            return err
+        }
+        fmt.Fprintf(r.Out, "watching %s = %02X\n", w.Name, w.value)
+    case "u", "unwatch":
+        if len(args) != 1 {
+            return fmt.Errorf("expected a register or address")
+        }
+        name, err := watchName(args[0])
+        if err != nil {
+            return err
+        }
+        if !d.Unwatch(name) {
+            return fmt.Errorf("not watching %s", name)
+        }
+    case "l", "list":
+        for _, addr := range d.Breakpoints() {
+            fmt.Fprintf(r.Out, "break %03X\n", addr)
+        }
+        for _, w := range d.Watches() {
+            fmt.Fprintf(r.Out, "watch %s = %02X\n", w.Name, w.value)
+        }
+    case "r", "regs":
+        r.printState()
+    case "x":
+        return r.examine(args)
+    case "press", "release":
+        if len(args) != 1 {
+            return fmt.Errorf("expected a key")
+        }
+        key, err := strconv.ParseUint(args[0], 16, 8)
+        if err != nil || key >= chip8.KeyCount {
+            return fmt.Errorf("invalid key: %q", args[0])
+        }
+        d.CPU.Keypad[key] = command == "press"
+    case "h", "help":
+        fmt.Fprint(r.Out, help)
+    default:
+        return fmt.Errorf("unknown command %q, enter help for a list of commands", command)
+    }
+    return nil
+}
+
+// watch adds a watchpoint for a register name or memory address
+func (r *REPL) watch(target string) (*Watch, error) {
+    t := strings.ToLower(target)
+    if t == "i" {
+        return r.Debugger.WatchIndex(), nil
+    }
+    if len(t) == 2 && t[0] == 'v' {
+        x, err := strconv.ParseUint(t[1:], 16, 8)
+        if err != nil {
+            return nil, fmt.Errorf("invalid register: %q", target)
+        }
+        return r.Debugger.WatchRegister(int(x)), nil
+    }
+    addr, err := parseAddress(target)
+    if err != nil {
+        return nil, err
+    }
+    return r.Debugger.WatchMemory(addr), nil
+}
+
+// watchName returns the name of the watchpoint for a target
+func watchName(target string) (string, error) {
+    t := strings.ToLower(target)
+    if t == "i" || len(t) == 2 && t[0] == 'v' {
+        return strings.ToUpper(t), nil
+    }
+    addr, err := parseAddress(target)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("[%03X]", addr), nil
+}
+
+func (r *REPL) examine(args []string) error {
+    if len(args) < 1 || len(args) > 2 {
+        return fmt.Errorf("expected an address and an optional count")
+    }
+    addr, err := parseAddress(args[0])
+    if err != nil {
+        return err
+    }
+    n := 16
+    if len(args) == 2 {
+        if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+            return fmt.Errorf("invalid count: %q", args[1])
+        }
+    }
+    memory := r.Debugger.CPU.Memory[:]
+    end := int(addr) + n
+    if end > len(memory) {
+        end = len(memory)
+    }
+    for line := int(addr); line < end; line += 16 {
+        last := line + 16
+        if last > end {
+            last = end
+        }
+        fmt.Fprintf(r.Out, "%03X: % X\n", line, memory[line:last])
+    }
+    return nil
+}
+
+func (r *REPL) printStop(stop Stop) {
+    switch stop.Reason {
+    case Watchpoint:
+        fmt.Fprintf(r.Out, "watchpoint %s: %02X -> %02X\n", stop.Watch.Name, stop.Old, stop.New)
+    case Halted:
+        fmt.Fprintf(r.Out, "halted: %v\n", stop.Err)
+    case Breakpoint, Limit:
+        fmt.Fprintln(r.Out, stop.Reason)
+    }
+    r.printState()
+}
+
+// printState shows the next instruction, registers, stack and timers
+func (r *REPL) printState() {
+    c := r.Debugger.CPU
+    text := "??"
+    if opCode, ok := r.Debugger.OpCode(); ok {
+        in := chip8.Decode(opCode)
+        if text, ok = in.Mnemonic(nil); !ok {
+            text = fmt.Sprintf("%04X (invalid)", opCode)
+        }
+        if pc := int(c.ProgramCounter); in.Op == chip8.OpLD_I_LONG && pc + 3 < len(c.Memory) {
+            text += fmt.Sprintf(" 0x%02X%02X", c.Memory[pc + 2], c.Memory[pc + 3])
+        }
+    }
+    fmt.Fprintf(r.Out, "PC %03X: %s\n", c.ProgramCounter, text)
+    for x := 0; x < len(c.Register); x++ {
+        fmt.Fprintf(r.Out, "V%X=%02X", x, c.Register[x])
+        if x % 8 == 7 {
+            fmt.Fprintln(r.Out)
+        } else {
+            fmt.Fprint(r.Out, " ")
+        }
+    }
+    fmt.Fprintf(r.Out, "I=%03X SP=%X DT=%02X ST=%02X\n", c.Index, c.StackPointer, c.DelayTimer, c.SoundTimer)
+    if c.StackPointer > 0 {
+        fmt.Fprint(r.Out, "stack:")
+        for i := 0; i < int(c.StackPointer) && i < len(c.Stack); i++ {
+            fmt.Fprintf(r.Out, " %03X", c.Stack[i])
+        }
+        fmt.Fprintln(r.Out)
+    }
+}
+
+// parseAddress parses a hexadecimal address, with or without 0x prefix
+func parseAddress(s string) (uint16, error) {
+    v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 16)
+    if err != nil || v >= chip8.MemorySize {
+        return 0, fmt.Errorf("invalid address: %q", s)
+    }
+    return uint16(v), nil
+}
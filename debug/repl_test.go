@@ -0,0 +1,54 @@
+package debug
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "strings"
+    "testing"
+)
+
+func Test_REPL(t *testing.T) {
+    d := newDebugger(t,
+        chip8.LD(0x1, 0x12),
+        chip8.LD_VX_K(0x2),
+        chip8.LDI(0x300),
+        chip8.LD_I_VX(0x2),
+        chip8.JP(0x208),
+    )
+    in := strings.NewReader(strings.Join([]string{
+        "step",
+        "b 204",
+        "press a",
+        "c",
+        "release a",
+        "c",
+        "w 0x302",
+        "c",
+        "x 300 4",
+        "bogus",
+        "quit",
+        "step",
+    }, "\n"))
+    out := &bytes.Buffer{}
+
+    if err := NewREPL(d, in, out).Run(); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, expected := range []string{
+        "PC 200: ld v1, 0x12",
+        "V0=00 V1=12",
+        "breakpoint\nPC 204: ld i, 0x300",
+        "watching [302] = 00",
+        "watchpoint [302]: 00 -> 0A",
+        "300: 00 12 0A 00",
+        `error: unknown command "bogus"`,
+    } {
+        if !strings.Contains(out.String(), expected) {
+            t.Errorf("output should contain %q:\n%s", expected, out.String())
+        }
+    }
+    if d.CPU.Register[0x2] != 0xA {
+        t.Errorf("key should be read: %v", d.CPU.Register[0x2])
+    }
+}
@@ -1,8 +1,22 @@
 module chip8-emulator
 
-go 1.14
+go 1.18
 
 require (
 	github.com/faiface/pixel v0.9.0
+	github.com/hajimehoshi/oto v0.7.1
 	golang.org/x/image v0.0.0-20200801110659-972c09e46d76
+	golang.org/x/term v0.1.0
+)
+
+require (
+	github.com/faiface/glhf v0.0.0-20181018222622-82a6317ac380 // indirect
+	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3 // indirect
+	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7 // indirect
+	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1 // indirect
+	github.com/go-gl/mathgl v0.0.0-20190416160123-c4601bc793c7 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	golang.org/x/exp v0.0.0-20190306152737-a1d7652674e8 // indirect
+	golang.org/x/mobile v0.0.0-20190415191353-3e0bab5405d6 // indirect
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 )
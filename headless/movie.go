@@ -0,0 +1,23 @@
+package headless
+
+import (
+    "chip8-emulator/chip8"
+    "chip8-emulator/movie"
+)
+
+// MovieEvents returns the events that press and release the keys as they
+// were recorded in a movie, so the runner plays it back
+func MovieEvents(m *movie.Movie) []Event {
+    var events []Event
+    var held [chip8.KeyCount]bool
+    for frame := range m.Frames {
+        keys := m.Keys(frame)
+        for k := range keys {
+            if keys[k] != held[k] {
+                events = append(events, Event{Frame: frame, Key: k, Pressed: keys[k]})
+            }
+        }
+        held = keys
+    }
+    return events
+}
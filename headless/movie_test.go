@@ -0,0 +1,58 @@
+package headless
+
+import (
+    "chip8-emulator/chip8"
+    "chip8-emulator/movie"
+    "testing"
+)
+
+type scriptedInput struct {
+    frame int
+}
+
+// KeyState holds key 5 in frames 3 to 5 and key A in frames 8 and 9
+func (i *scriptedInput) KeyState() [chip8.KeyCount]bool {
+    var keys [chip8.KeyCount]bool
+    keys[0x5] = i.frame >= 3 && i.frame <= 5
+    keys[0xA] = i.frame >= 8 && i.frame <= 9
+    return keys
+}
+
+func Test_MovieEvents_replay(t *testing.T) {
+    program := []uint16{
+        chip8.LD_VX_K(0x1),
+        chip8.RND(0x2, 0xFF),
+        chip8.ADD_R(0x3, 0x1),
+        chip8.JP(0x200),
+    }
+
+    // record a session, reading the input once per frame
+    recorded := newCPU(t, program...)
+    recorded.Seed(99)
+    source := &scriptedInput{}
+    m := &movie.Movie{Seed: 99, ClockSpeed: 120}
+    recorder := movie.NewRecorder(source, m)
+    recorded.Input = recorder
+    scheduler := chip8.NewScheduler(recorded, m.ClockSpeed)
+    for source.frame = 0; source.frame < 12; source.frame++ {
+        recorder.Frame()
+        if err := scheduler.Frame(); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // and play it back with the runner
+    replayed := newCPU(t, program...)
+    replayed.Seed(m.Seed)
+    r := NewRunner(replayed, m.ClockSpeed, MovieEvents(m))
+    if err := r.RunFrames(len(m.Frames)); err != nil {
+        t.Fatal(err)
+    }
+
+    if replayed.Register != recorded.Register || replayed.ProgramCounter != recorded.ProgramCounter {
+        t.Errorf("replay does not match:\n%v\n%v", replayed.Register, recorded.Register)
+    }
+    if recorded.Register[0x3] != 0x5 + 0xA {
+        t.Errorf("both keys should have been read: %v", recorded.Register[0x3])
+    }
+}
@@ -0,0 +1,79 @@
+package headless
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "image"
+    "image/png"
+    "io"
+    "strings"
+)
+
+// textPixels are the characters for the pixels in text dumps: unlit, lit
+// on the first plane, on the second plane and on both planes
+const textPixels = ".#o@"
+
+// Text renders the display at its current resolution as one line of
+// characters per row
+func Text(c *chip8.CPU) string {
+    width, height := c.Resolution()
+    var b strings.Builder
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            b.WriteByte(textPixels[c.DisplayBuffer[x][y] % byte(len(textPixels))])
+        }
+        b.WriteByte('\n')
+    }
+    return b.String()
+}
+
+// Image renders the display at its current resolution, with every pixel as
+// a square of scale by scale
+func Image(c *chip8.CPU, scale int, palette chip8.Palette) *image.RGBA {
+    width, height := c.Resolution()
+    img := image.NewRGBA(image.Rect(0, 0, width * scale, height * scale))
+    for y := 0; y < img.Bounds().Dy(); y++ {
+        for x := 0; x < img.Bounds().Dx(); x++ {
+            img.Set(x, y, palette[int(c.DisplayBuffer[x / scale][y / scale]) % len(palette)])
+        }
+    }
+    return img
+}
+
+// WritePNG encodes the display as a PNG image
+func WritePNG(w io.Writer, c *chip8.CPU, scale int, palette chip8.Palette) error {
+    return png.Encode(w, Image(c, scale, palette))
+}
+
+// EqualImages returns true if both images have the same size and pixels
+func EqualImages(a, b image.Image) bool {
+    if a.Bounds().Size() != b.Bounds().Size() {
+        return false
+    }
+    ao := a.Bounds().Min
+    bo := b.Bounds().Min
+    for y := 0; y < a.Bounds().Dy(); y++ {
+        for x := 0; x < a.Bounds().Dx(); x++ {
+            r1, g1, b1, a1 := a.At(ao.X + x, ao.Y + y).RGBA()
+            r2, g2, b2, a2 := b.At(bo.X + x, bo.Y + y).RGBA()
+            if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// EqualPNG returns true if two PNG files contain the same image, regardless
+// of how they were encoded
+func EqualPNG(a, b []byte) (bool, error) {
+    ia, err := png.Decode(bytes.NewReader(a))
+    if err != nil {
+        return false, err
+    }
+    ib, err := png.Decode(bytes.NewReader(b))
+    if err != nil {
+        return false, err
+    }
+    return EqualImages(ia, ib), nil
+}
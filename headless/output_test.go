@@ -0,0 +1,90 @@
+package headless
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "image/color"
+    "strings"
+    "testing"
+)
+
+func Test_Text(t *testing.T) {
+    c := newCPU(t)
+    c.DisplayBuffer[0][0] = 1
+    c.DisplayBuffer[63][31] = 1
+
+    lines := strings.Split(Text(c), "\n")
+
+    if len(lines) != chip8.DisplayHeight + 1 {
+        t.Fatalf("unexpected number of lines: %d", len(lines))
+    }
+    if lines[0] != "#" + strings.Repeat(".", 63) || lines[31] != strings.Repeat(".", 63) + "#" {
+        t.Errorf("unexpected text:\n%s", Text(c))
+    }
+}
+
+func Test_Text_high_resolution(t *testing.T) {
+    c := newCPU(t, chip8.HIGH())
+    c.Quirks = chip8.QuirksSuperChip
+    c.Cycle()
+    c.DisplayBuffer[127][63] = 1
+
+    lines := strings.Split(Text(c), "\n")
+
+    if len(lines) != chip8.HiResHeight + 1 || lines[63] != strings.Repeat(".", 127) + "#" {
+        t.Errorf("unexpected text:\n%s", Text(c))
+    }
+}
+
+func Test_Text_planes(t *testing.T) {
+    c := newCPU(t)
+    c.DisplayBuffer[1][0] = 1
+    c.DisplayBuffer[2][0] = 2
+    c.DisplayBuffer[3][0] = 3
+
+    if line := strings.Split(Text(c), "\n")[0]; !strings.HasPrefix(line, ".#o@.") {
+        t.Errorf("unexpected text: %s", line)
+    }
+}
+
+func Test_Image_palette(t *testing.T) {
+    c := newCPU(t)
+    c.DisplayBuffer[0][0] = 2
+    c.DisplayBuffer[1][0] = 3
+    red := color.RGBA{255, 0, 0, 255}
+    blue := color.RGBA{0, 0, 255, 255}
+
+    img := Image(c, 1, chip8.Palette{color.Black, color.White, red, blue})
+
+    if img.RGBAAt(0, 0) != red || img.RGBAAt(1, 0) != blue {
+        t.Errorf("unexpected pixels: %v %v", img.RGBAAt(0, 0), img.RGBAAt(1, 0))
+    }
+}
+
+func Test_PNG(t *testing.T) {
+    c := newCPU(t)
+    c.DisplayBuffer[1][0] = 1
+
+    img := Image(c, 2, chip8.DefaultPalette)
+
+    if img.Bounds().Dx() != 128 || img.Bounds().Dy() != 64 {
+        t.Errorf("unexpected size: %v", img.Bounds())
+    }
+    if img.RGBAAt(2, 1) != (color.RGBA{255, 255, 255, 255}) || img.RGBAAt(1, 1) != (color.RGBA{0, 0, 0, 255}) {
+        t.Error("unexpected pixels")
+    }
+
+    var a, b bytes.Buffer
+    WritePNG(&a, c, 2, chip8.DefaultPalette)
+    WritePNG(&b, c, 2, chip8.DefaultPalette)
+    if equal, err := EqualPNG(a.Bytes(), b.Bytes()); !equal || err != nil {
+        t.Errorf("same display should be equal: %v", err)
+    }
+
+    c.DisplayBuffer[1][0] = 0
+    b.Reset()
+    WritePNG(&b, c, 2, chip8.DefaultPalette)
+    if equal, err := EqualPNG(a.Bytes(), b.Bytes()); equal || err != nil {
+        t.Errorf("different displays should not be equal: %v", err)
+    }
+}
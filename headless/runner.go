@@ -0,0 +1,140 @@
+// Package headless runs ROMs without a window, driven by scripted input, and
+// renders the display to PNG or text so it can be compared against golden
+// files in regression tests.
+package headless
+
+import (
+    "bufio"
+    "chip8-emulator/chip8"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Event presses or releases a key at the start of a frame
+type Event struct {
+    Frame   int
+    Key     int
+    Pressed bool
+}
+
+// ParseScript reads input events, one per line, in the form
+//
+//   <frame> press <key>
+//   <frame> release <key>
+//
+// where key is a hexadecimal digit. Empty lines and lines starting with #
+// are ignored. The events are returned sorted by frame.
+func ParseScript(r io.Reader) ([]Event, error) {
+    var events []Event
+    scanner := bufio.NewScanner(r)
+    for line := 1; scanner.Scan(); line++ {
+        text := strings.TrimSpace(scanner.Text())
+        if text == "" || strings.HasPrefix(text, "#") {
+            continue
+        }
+        fields := strings.Fields(text)
+        if len(fields) != 3 {
+            return nil, fmt.Errorf("line %d: expected <frame> press|release <key>", line)
+        }
+        frame, err := strconv.Atoi(fields[0])
+        if err != nil || frame < 0 {
+            return nil, fmt.Errorf("line %d: invalid frame: %q", line, fields[0])
+        }
+        var pressed bool
+        switch fields[1] {
+        case "press":
+            pressed = true
+        case "release":
+            pressed = false
+        default:
+            return nil, fmt.Errorf("line %d: unknown action: %q", line, fields[1])
+        }
+        key, err := strconv.ParseUint(fields[2], 16, 8)
+        if err != nil || key >= chip8.KeyCount {
+            return nil, fmt.Errorf("line %d: invalid key: %q", line, fields[2])
+        }
+        events = append(events, Event{Frame: frame, Key: int(key), Pressed: pressed})
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    sort.SliceStable(events, func(i, j int) bool { return events[i].Frame < events[j].Frame })
+    return events, nil
+}
+
+// Runner executes a CPU at a fixed clock speed, applying input events at the
+// start of their frame
+type Runner struct {
+    CPU       *chip8.CPU
+    Scheduler *chip8.Scheduler
+    Events    []Event
+    // Executor executes the instructions, or the CPU itself if it is nil
+    Executor  chip8.Executor
+
+    // Frames and Cycles count the frames started and instructions executed
+    Frames int
+    Cycles int
+    // Exited is set when the program exits with the SUPER-CHIP 00FD
+    // instruction, after which nothing more is executed
+    Exited bool
+
+    next int
+}
+
+func NewRunner(cpu *chip8.CPU, clockSpeed int, events []Event) *Runner {
+    return &Runner{
+        CPU:       cpu,
+        Scheduler: chip8.NewScheduler(cpu, clockSpeed),
+        Events:    events,
+    }
+}
+
+// RunFrames executes n frames
+func (r *Runner) RunFrames(n int) error {
+    return r.run(r.Frames + n, -1)
+}
+
+// RunCycles executes n instructions. The last frame is cut short if n does
+// not end on a frame boundary.
+func (r *Runner) RunCycles(n int) error {
+    return r.run(-1, r.Cycles + n)
+}
+
+// run executes frames until the frame or cycle count reaches its limit, or
+// the program exits. A negative limit is ignored.
+func (r *Runner) run(frames, cycles int) error {
+    executor := r.Executor
+    if executor == nil {
+        executor = r.CPU
+    }
+    for !r.Exited && (frames < 0 || r.Frames < frames) {
+        for r.next < len(r.Events) && r.Events[r.next].Frame <= r.Frames {
+            e := r.Events[r.next]
+            r.CPU.Keypad[e.Key] = e.Pressed
+            r.next++
+        }
+        r.Frames++
+        n := r.Scheduler.FrameCycles()
+        last := cycles >= 0 && n > cycles - r.Cycles
+        if last {
+            n = cycles - r.Cycles
+        }
+        done, err := executor.Run(n)
+        r.Cycles += done
+        if errors.Is(err, chip8.ErrExit) {
+            r.Exited = true
+            return nil
+        } else if err != nil {
+            return err
+        }
+        if last {
+            return nil
+        }
+        r.CPU.TickTimers()
+    }
+    return nil
+}
@@ -0,0 +1,154 @@
+package headless
+
+import (
+    "chip8-emulator/chip8"
+    "errors"
+    "strings"
+    "testing"
+)
+
+func newCPU(t *testing.T, ops ...uint16) *chip8.CPU {
+    t.Helper()
+    c, err := chip8.NewCPU(chip8.Build(ops...))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return c
+}
+
+func Test_ParseScript(t *testing.T) {
+    events, err := ParseScript(strings.NewReader(`
+        # comment
+        10 release a
+        2 press A
+    `))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []Event{
+        {Frame: 2, Key: 0xA, Pressed: true},
+        {Frame: 10, Key: 0xA, Pressed: false},
+    }
+    if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+        t.Errorf("unexpected events: %+v", events)
+    }
+}
+
+func Test_ParseScript_errors(t *testing.T) {
+    for _, script := range []string{
+        "1 press",
+        "x press 1",
+        "1 hold 1",
+        "1 press 10",
+    } {
+        if _, err := ParseScript(strings.NewReader(script)); err == nil || !strings.HasPrefix(err.Error(), "line 1:") {
+            t.Errorf("%q: unexpected error: %v", script, err)
+        }
+    }
+}
+
+func Test_RunFrames(t *testing.T) {
+    c := newCPU(t,
+        chip8.ADD(0x1, 0x1),
+        chip8.JP(0x200),
+    )
+    c.DelayTimer = 10
+    r := NewRunner(c, 600, nil)
+
+    if err := r.RunFrames(3); err != nil {
+        t.Fatal(err)
+    }
+
+    if r.Cycles != 30 || r.Frames != 3 || c.Register[0x1] != 15 {
+        t.Errorf("unexpected run: %d cycles, %d frames, v1 %d", r.Cycles, r.Frames, c.Register[0x1])
+    }
+    if c.DelayTimer != 7 {
+        t.Errorf("unexpected delay timer: %v", c.DelayTimer)
+    }
+}
+
+func Test_RunCycles(t *testing.T) {
+    c := newCPU(t,
+        chip8.JP(0x200),
+    )
+    r := NewRunner(c, 600, nil)
+
+    if err := r.RunCycles(25); err != nil {
+        t.Fatal(err)
+    }
+
+    if r.Cycles != 25 || r.Frames != 3 {
+        t.Errorf("unexpected run: %d cycles, %d frames", r.Cycles, r.Frames)
+    }
+}
+
+func Test_Run_events(t *testing.T) {
+    c := newCPU(t,
+        chip8.LD_VX_K(0x1),
+        chip8.JP(0x202),
+    )
+    events := []Event{
+        {Frame: 2, Key: 0x7, Pressed: true},
+        {Frame: 4, Key: 0x7, Pressed: false},
+    }
+    r := NewRunner(c, 600, events)
+
+    r.RunFrames(4)
+
+    if c.ProgramCounter != 0x200 {
+        t.Errorf("should wait for the key to be released: %x", c.ProgramCounter)
+    }
+
+    r.RunFrames(1)
+
+    if c.ProgramCounter != 0x202 || c.Register[0x1] != 0x7 {
+        t.Errorf("unexpected state: pc %x, v1 %v", c.ProgramCounter, c.Register[0x1])
+    }
+}
+
+func Test_Run_error(t *testing.T) {
+    c := newCPU(t,
+        chip8.RET(),
+    )
+    r := NewRunner(c, 600, nil)
+
+    if err := r.RunFrames(1); !errors.Is(err, chip8.ErrStackUnderflow) {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
+
+func Test_Run_exit(t *testing.T) {
+    c := newCPU(t,
+        chip8.LD(0x1, 0x1),
+        chip8.EXIT(),
+        chip8.LD(0x1, 0x2),
+    )
+    c.Quirks = chip8.QuirksSuperChip
+    r := NewRunner(c, 600, nil)
+
+    if err := r.RunFrames(2); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if !r.Exited || r.Cycles != 1 || c.Register[0x1] != 0x1 {
+        t.Errorf("should stop at exit: cycles %d, v1 %v", r.Cycles, c.Register[0x1])
+    }
+}
+
+func Test_Run_Executor(t *testing.T) {
+    c := newCPU(t,
+        chip8.ADD(0x1, 0x1),
+        chip8.JP(0x200),
+    )
+    c.DelayTimer = 10
+    r := NewRunner(c, 600, nil)
+    r.Executor = chip8.NewBlockCache(c)
+
+    if err := r.RunCycles(25); err != nil {
+        t.Fatal(err)
+    }
+
+    if r.Cycles != 25 || r.Frames != 3 || c.Register[0x1] != 13 || c.DelayTimer != 8 {
+        t.Errorf("unexpected run: %d cycles, %d frames, v1 %v, dt %v", r.Cycles, r.Frames, c.Register[0x1], c.DelayTimer)
+    }
+}
@@ -0,0 +1,64 @@
+package movie
+
+import (
+    "chip8-emulator/chip8"
+)
+
+// Recorder is a chip8.InputSource that records the keys of another source.
+// The keys are read once at the start of every frame, so they do not change
+// within a frame, and appended to the movie.
+type Recorder struct {
+    Source chip8.InputSource
+    Movie  *Movie
+    keys   [chip8.KeyCount]bool
+}
+
+func NewRecorder(source chip8.InputSource, m *Movie) *Recorder {
+    return &Recorder{
+        Source: source,
+        Movie:  m,
+    }
+}
+
+// Frame reads and records the keys for the next frame. It must be called
+// before every frame is run.
+func (r *Recorder) Frame() {
+    r.keys = r.Source.KeyState()
+    var mask uint16
+    for k, pressed := range r.keys {
+        if pressed {
+            mask |= 1 << k
+        }
+    }
+    r.Movie.Frames = append(r.Movie.Frames, mask)
+}
+
+func (r *Recorder) KeyState() [chip8.KeyCount]bool {
+    return r.keys
+}
+
+// Player is a chip8.InputSource that plays back the keys of a movie
+type Player struct {
+    Movie *Movie
+    // Frames counts the frames played
+    Frames int
+}
+
+func NewPlayer(m *Movie) *Player {
+    return &Player{Movie: m}
+}
+
+// Frame advances to the keys of the next frame. It must be called before
+// every frame is run.
+func (p *Player) Frame() {
+    p.Frames++
+}
+
+// Done returns true once all frames of the movie were played
+func (p *Player) Done() bool {
+    return p.Frames >= len(p.Movie.Frames)
+}
+
+func (p *Player) KeyState() [chip8.KeyCount]bool {
+    return p.Movie.Keys(p.Frames - 1)
+}
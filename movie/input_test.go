@@ -0,0 +1,48 @@
+package movie
+
+import (
+    "chip8-emulator/chip8"
+    "testing"
+)
+
+type testInput struct {
+    keys [chip8.KeyCount]bool
+}
+
+func (i *testInput) KeyState() [chip8.KeyCount]bool {
+    return i.keys
+}
+
+func Test_Recorder_Player(t *testing.T) {
+    source := &testInput{}
+    m := &Movie{}
+    r := NewRecorder(source, m)
+
+    r.Frame()
+    source.keys[0x5] = true
+    r.Frame()
+    // changes within a frame are not seen until the next frame
+    source.keys[0x6] = true
+    if keys := r.KeyState(); !keys[0x5] || keys[0x6] {
+        t.Errorf("unexpected keys: %v", keys)
+    }
+    r.Frame()
+
+    if len(m.Frames) != 3 || m.Frames[0] != 0 || m.Frames[1] != 0x20 || m.Frames[2] != 0x60 {
+        t.Fatalf("unexpected frames: %v", m.Frames)
+    }
+
+    p := NewPlayer(m)
+    for frame := 0; frame < 3; frame++ {
+        if p.Done() {
+            t.Fatal("player should not be done")
+        }
+        p.Frame()
+        if p.KeyState() != m.Keys(frame) {
+            t.Errorf("unexpected keys in frame %d", frame)
+        }
+    }
+    if !p.Done() {
+        t.Error("player should be done")
+    }
+}
@@ -0,0 +1,202 @@
+// Package movie records the keypad input of every frame, together with the
+// settings that affect the emulation, so a session can be replayed exactly.
+//
+// Movies are text files, so they can be attached to bug reports and read:
+//
+//   chip8-movie 1
+//   rom 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//   quirks modern
+//   seed 1234
+//   speed 600
+//   frames
+//   0000 120
+//   0010 5
+//
+// Every line after "frames" holds the keypad state as a hexadecimal bitmask
+// with bit n set while key n is held, and the number of frames it lasts.
+package movie
+
+import (
+    "bufio"
+    "chip8-emulator/chip8"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// Version is the version of the movie format written by Write
+const Version = 1
+
+// header starts every movie
+const header = "chip8-movie"
+
+var (
+    // ErrInvalidMovie is returned when reading a file that is not a movie
+    ErrInvalidMovie = errors.New("invalid movie")
+    // ErrWrongROM is returned when a movie is played with another rom than
+    // it was recorded with
+    ErrWrongROM = errors.New("movie was recorded with a different rom")
+)
+
+// Movie is a recorded session
+type Movie struct {
+    // ROM is the SHA-256 hash of the rom, as returned by Hash
+    ROM        string
+    // Quirks is the name of the quirk profile
+    Quirks     string
+    Seed       int64
+    ClockSpeed int
+    // Frames holds the keypad state of every frame, with bit n set while
+    // key n is held
+    Frames     []uint16
+}
+
+// Hash returns the hash that identifies a rom in a movie
+func Hash(rom []byte) string {
+    sum := sha256.Sum256(rom)
+    return hex.EncodeToString(sum[:])
+}
+
+// Check returns ErrWrongROM if the movie was not recorded with rom
+func (m *Movie) Check(rom []byte) error {
+    if m.ROM != Hash(rom) {
+        return ErrWrongROM
+    }
+    return nil
+}
+
+// Keys returns the keypad state of a frame, or no keys held after the end
+// of the movie
+func (m *Movie) Keys(frame int) [chip8.KeyCount]bool {
+    var keys [chip8.KeyCount]bool
+    if frame < 0 || frame >= len(m.Frames) {
+        return keys
+    }
+    for k := range keys {
+        keys[k] = m.Frames[frame] & (1 << k) != 0
+    }
+    return keys
+}
+
+// Write writes the movie in the text format
+func (m *Movie) Write(w io.Writer) error {
+    b := bufio.NewWriter(w)
+    fmt.Fprintf(b, "%s %d\n", header, Version)
+    fmt.Fprintf(b, "rom %s\n", m.ROM)
+    fmt.Fprintf(b, "quirks %s\n", m.Quirks)
+    fmt.Fprintf(b, "seed %d\n", m.Seed)
+    fmt.Fprintf(b, "speed %d\n", m.ClockSpeed)
+    fmt.Fprintln(b, "frames")
+    for i := 0; i < len(m.Frames); {
+        n := 1
+        for i + n < len(m.Frames) && m.Frames[i + n] == m.Frames[i] {
+            n++
+        }
+        fmt.Fprintf(b, "%04X %d\n", m.Frames[i], n)
+        i += n
+    }
+    return b.Flush()
+}
+
+// Read reads a movie written by Write
+func Read(r io.Reader) (*Movie, error) {
+    m := &Movie{}
+    scanner := bufio.NewScanner(r)
+    invalid := func(line int, format string, args ...interface{}) error {
+        return fmt.Errorf("%w: line %d: %s", ErrInvalidMovie, line, fmt.Sprintf(format, args...))
+    }
+
+    line := 0
+    next := func() ([]string, bool) {
+        if !scanner.Scan() {
+            return nil, false
+        }
+        line++
+        return strings.Fields(scanner.Text()), true
+    }
+
+    fields, ok := next()
+    if !ok || len(fields) != 2 || fields[0] != header {
+        return nil, fmt.Errorf("%w: missing header", ErrInvalidMovie)
+    }
+    if fields[1] != strconv.Itoa(Version) {
+        return nil, fmt.Errorf("%w: unsupported version %s", ErrInvalidMovie, fields[1])
+    }
+
+    // the settings, up to the frames
+    for {
+        fields, ok = next()
+        if !ok {
+            return nil, fmt.Errorf("%w: missing frames", ErrInvalidMovie)
+        }
+        if len(fields) == 1 && fields[0] == "frames" {
+            break
+        }
+        if len(fields) != 2 {
+            return nil, invalid(line, "expected <setting> <value>")
+        }
+        var err error
+        switch fields[0] {
+        case "rom":
+            m.ROM = fields[1]
+        case "quirks":
+            m.Quirks = fields[1]
+        case "seed":
+            m.Seed, err = strconv.ParseInt(fields[1], 10, 64)
+        case "speed":
+            m.ClockSpeed, err = strconv.Atoi(fields[1])
+        default:
+            return nil, invalid(line, "unknown setting: %q", fields[0])
+        }
+        if err != nil {
+            return nil, invalid(line, "invalid %s: %q", fields[0], fields[1])
+        }
+    }
+
+    for {
+        fields, ok = next()
+        if !ok {
+            break
+        }
+        if len(fields) == 0 {
+            continue
+        }
+        if len(fields) != 2 {
+            return nil, invalid(line, "expected <keys> <frames>")
+        }
+        keys, err := strconv.ParseUint(fields[0], 16, 16)
+        if err != nil {
+            return nil, invalid(line, "invalid keys: %q", fields[0])
+        }
+        n, err := strconv.Atoi(fields[1])
+        if err != nil || n <= 0 {
+            return nil, invalid(line, "invalid frame count: %q", fields[1])
+        }
+        for i := 0; i < n; i++ {
+            m.Frames = append(m.Frames, uint16(keys))
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// ReadFile reads a movie file written by Write
+func ReadFile(path string) (*Movie, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    m, err := Read(f)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return m, nil
+}
@@ -0,0 +1,114 @@
+package movie
+
+import (
+    "bytes"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func Test_Write_Read(t *testing.T) {
+    m := &Movie{
+        ROM:        Hash([]byte{0x00, 0xE0}),
+        Quirks:     "schip",
+        Seed:       -42,
+        ClockSpeed: 1000,
+        Frames:     []uint16{0, 0, 0, 0x10, 0x10, 0x8001, 0},
+    }
+    var b bytes.Buffer
+    if err := m.Write(&b); err != nil {
+        t.Fatal(err)
+    }
+
+    if !strings.Contains(b.String(), "frames\n0000 3\n0010 2\n8001 1\n0000 1\n") {
+        t.Errorf("frames should be run-length encoded:\n%s", b.String())
+    }
+
+    read, err := Read(&b)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if read.ROM != m.ROM || read.Quirks != "schip" || read.Seed != -42 || read.ClockSpeed != 1000 {
+        t.Errorf("unexpected settings: %+v", read)
+    }
+    if len(read.Frames) != len(m.Frames) {
+        t.Fatalf("unexpected frames: %v", read.Frames)
+    }
+    for i := range m.Frames {
+        if read.Frames[i] != m.Frames[i] {
+            t.Errorf("unexpected frame %d: %04X", i, read.Frames[i])
+        }
+    }
+}
+
+func Test_Read_errors(t *testing.T) {
+    inputs := []string{
+        "",
+        "chip8-movie 2\nframes\n",
+        "chip8-movie 1\nseed 1\n",
+        "chip8-movie 1\nseed x\nframes\n",
+        "chip8-movie 1\ncolor red\nframes\n",
+        "chip8-movie 1\nframes\n0000\n",
+        "chip8-movie 1\nframes\nXYZW 1\n",
+        "chip8-movie 1\nframes\n0000 0\n",
+    }
+    for _, input := range inputs {
+        if _, err := Read(strings.NewReader(input)); !errors.Is(err, ErrInvalidMovie) {
+            t.Errorf("unexpected error for %q: %v", input, err)
+        }
+    }
+}
+
+func Test_ReadFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "movie")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "test.movie")
+    if err := ioutil.WriteFile(path, []byte("chip8-movie 1\nseed 7\nframes\n0001 2\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    m, err := ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if m.Seed != 7 || len(m.Frames) != 2 {
+        t.Errorf("unexpected movie: %+v", m)
+    }
+
+    ioutil.WriteFile(path, []byte("chip8-movie 1\n"), 0644)
+    if _, err := ReadFile(path); !errors.Is(err, ErrInvalidMovie) || !strings.Contains(err.Error(), path) {
+        t.Errorf("expected an error naming the file, got %v", err)
+    }
+    if _, err := ReadFile(filepath.Join(dir, "missing.movie")); !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("expected a missing file to fail, got %v", err)
+    }
+}
+
+func Test_Check(t *testing.T) {
+    m := &Movie{ROM: Hash([]byte{1, 2, 3})}
+
+    if err := m.Check([]byte{1, 2, 3}); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if err := m.Check([]byte{1, 2, 4}); err != ErrWrongROM {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
+
+func Test_Keys(t *testing.T) {
+    m := &Movie{Frames: []uint16{0x8001}}
+
+    keys := m.Keys(0)
+    if !keys[0x0] || !keys[0xF] || keys[0x1] {
+        t.Errorf("unexpected keys: %v", keys)
+    }
+    if m.Keys(1) != m.Keys(-1) || m.Keys(1)[0] {
+        t.Error("no keys should be held outside the movie")
+    }
+}
@@ -0,0 +1,149 @@
+// Package rewind keeps a history of machine states, so the emulation can be
+// stepped backwards frame by frame.
+//
+// Only the newest state is stored in full. Every older state is stored as
+// the bytes that differ from the state after it, which are mostly a few
+// bytes of the display and memory.
+package rewind
+
+import (
+    "chip8-emulator/chip8"
+    "errors"
+)
+
+// DefaultDepth is the number of states kept, 10 seconds at 60 frames per
+// second
+const DefaultDepth = 10 * chip8.TimerFrequency
+
+// ErrEmpty is returned when rewinding without any states left
+var ErrEmpty = errors.New("no history to rewind")
+
+// run is a sequence of bytes at an offset in a saved state
+type run struct {
+    offset int
+    data   []byte
+}
+
+// delta turns a saved state into the state before it, which has size
+// bytes. States differ in length when the display planes or the address
+// space in use change.
+type delta struct {
+    size int
+    runs []run
+}
+
+// History is a ring buffer of the last Depth machine states
+type History struct {
+    depth  int
+    latest []byte
+    // deltas is a ring buffer of count deltas starting at start, the last
+    // of which turns latest into the state saved before it
+    deltas []delta
+    start  int
+    count  int
+}
+
+// New returns an empty history that keeps up to depth states
+func New(depth int) *History {
+    if depth < 1 {
+        depth = 1
+    }
+    return &History{
+        depth:  depth,
+        deltas: make([]delta, depth - 1),
+    }
+}
+
+// Len returns the number of states in the history
+func (h *History) Len() int {
+    if h.latest == nil {
+        return 0
+    }
+    return h.count + 1
+}
+
+// Size returns the number of bytes used to store the states
+func (h *History) Size() int {
+    size := len(h.latest)
+    for i := 0; i < h.count; i++ {
+        for _, r := range h.deltas[(h.start + i) % len(h.deltas)].runs {
+            size += len(r.data)
+        }
+    }
+    return size
+}
+
+// Clear removes all states
+func (h *History) Clear() {
+    h.latest = nil
+    h.start = 0
+    h.count = 0
+}
+
+// Save adds the current state of c, dropping the oldest state if the
+// history is full
+func (h *History) Save(c *chip8.CPU) error {
+    state, err := c.MarshalBinary()
+    if err != nil {
+        return err
+    }
+    if h.latest != nil && len(h.deltas) > 0 {
+        d := diff(state, h.latest)
+        if h.count == len(h.deltas) {
+            h.start = (h.start + 1) % len(h.deltas)
+            h.count--
+        }
+        h.deltas[(h.start + h.count) % len(h.deltas)] = d
+        h.count++
+    }
+    h.latest = state
+    return nil
+}
+
+// Rewind restores the newest state in the history and removes it, so the
+// next call restores the state before it
+func (h *History) Rewind(c *chip8.CPU) error {
+    if h.latest == nil {
+        return ErrEmpty
+    }
+    if err := c.UnmarshalBinary(h.latest); err != nil {
+        return err
+    }
+    if h.count == 0 {
+        h.latest = nil
+        return nil
+    }
+    last := (h.start + h.count - 1) % len(h.deltas)
+    h.latest = h.deltas[last].apply(h.latest)
+    h.deltas[last] = delta{}
+    h.count--
+    return nil
+}
+
+// diff returns the delta that turns from into to
+func diff(from, to []byte) delta {
+    d := delta{size: len(to)}
+    for i := 0; i < len(to); {
+        if i < len(from) && from[i] == to[i] {
+            i++
+            continue
+        }
+        start := i
+        for i < len(to) && (i >= len(from) || from[i] != to[i]) {
+            i++
+        }
+        d.runs = append(d.runs, run{start, append([]byte(nil), to[start:i]...)})
+    }
+    return d
+}
+
+// apply returns a copy of state resized to the size of the delta, with its
+// runs written over it
+func (d delta) apply(state []byte) []byte {
+    result := make([]byte, d.size)
+    copy(result, state)
+    for _, r := range d.runs {
+        copy(result[r.offset:], r.data)
+    }
+    return result
+}
@@ -0,0 +1,143 @@
+package rewind
+
+import (
+    "chip8-emulator/chip8"
+    "testing"
+)
+
+func newCPU(t *testing.T) *chip8.CPU {
+    t.Helper()
+    // counts up in V1 and draws a digit for every value
+    c, err := chip8.NewCPU(chip8.Build(
+        chip8.ADD(0x1, 1),
+        chip8.LDF(0x1),
+        chip8.DRW(0x0, 0x0, 5),
+        chip8.JP(0x200),
+    ))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return c
+}
+
+func Test_Rewind(t *testing.T) {
+    c := newCPU(t)
+    h := New(10)
+    var states [][]byte
+    for frame := 0; frame < 5; frame++ {
+        if err := h.Save(c); err != nil {
+            t.Fatal(err)
+        }
+        state, _ := c.MarshalBinary()
+        states = append(states, state)
+        for i := 0; i < 4; i++ {
+            c.Cycle()
+        }
+    }
+    if h.Len() != 5 {
+        t.Errorf("unexpected length: %d", h.Len())
+    }
+
+    for frame := 4; frame >= 0; frame-- {
+        if err := h.Rewind(c); err != nil {
+            t.Fatal(err)
+        }
+        state, _ := c.MarshalBinary()
+        if string(state) != string(states[frame]) {
+            t.Fatalf("state of frame %d was not restored", frame)
+        }
+    }
+    if err := h.Rewind(c); err != ErrEmpty {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
+
+func Test_Rewind_depth(t *testing.T) {
+    c := newCPU(t)
+    h := New(3)
+    for frame := 0; frame < 10; frame++ {
+        h.Save(c)
+        for i := 0; i < 4; i++ {
+            c.Cycle()
+        }
+    }
+
+    if h.Len() != 3 {
+        t.Errorf("unexpected length: %d", h.Len())
+    }
+    for h.Rewind(c) == nil {
+    }
+    // the oldest state kept is the one saved before the 8th frame
+    if c.Register[0x1] != 7 {
+        t.Errorf("unexpected V1: %d", c.Register[0x1])
+    }
+}
+
+func Test_Rewind_resume(t *testing.T) {
+    c := newCPU(t)
+    h := New(5)
+    for frame := 0; frame < 3; frame++ {
+        h.Save(c)
+        c.Cycle()
+    }
+    h.Rewind(c)
+    h.Save(c)
+    c.Cycle()
+
+    h.Rewind(c)
+    if c.Register[0x1] != 1 {
+        t.Errorf("unexpected V1: %d", c.Register[0x1])
+    }
+    h.Rewind(c)
+    h.Rewind(c)
+    if c.Register[0x1] != 0 || h.Len() != 0 {
+        t.Errorf("unexpected state: V1 %d, %d states left", c.Register[0x1], h.Len())
+    }
+}
+
+func Test_Size(t *testing.T) {
+    c := newCPU(t)
+    h := New(100)
+    for frame := 0; frame < 100; frame++ {
+        h.Save(c)
+        for i := 0; i < 4; i++ {
+            c.Cycle()
+        }
+    }
+    full, _ := c.MarshalBinary()
+
+    // only the registers and the digit on the display change
+    if h.Size() > len(full) + 99 * 200 {
+        t.Errorf("history is not compressed: %d bytes", h.Size())
+    }
+}
+
+func Test_Rewind_resized(t *testing.T) {
+    // the states grow and shrink with the display planes and resolution
+    c, err := chip8.NewCPU(chip8.Build(
+        chip8.DRW(0x0, 0x0, 5),
+        chip8.HIGH(),
+        chip8.DRW(0x0, 0x0, 5),
+        chip8.CLS(),
+    ))
+    if err != nil {
+        t.Fatal(err)
+    }
+    h := New(10)
+    var states [][]byte
+    for i := 0; i < 5; i++ {
+        h.Save(c)
+        state, _ := c.MarshalBinary()
+        states = append(states, state)
+        c.Cycle()
+    }
+
+    for i := len(states) - 1; i >= 0; i-- {
+        if err := h.Rewind(c); err != nil {
+            t.Fatal(err)
+        }
+        if state, _ := c.MarshalBinary(); string(state) != string(states[i]) {
+            t.Errorf("state %d was not restored", i)
+        }
+    }
+}
@@ -0,0 +1,14 @@
+package romdb
+
+// bundled is the database bundled with the emulator. It is a seed with the
+// IBM logo rom, which was rebuilt from its published listing, rather than a
+// catalogue of the known roms: users add the roms they run to their own
+// database. The hash of every entry is that of the copy of the rom in
+// testdata, which the tests check is found, so new entries need the rom
+// file and not only a hash from another database.
+const bundled = `{
+  "1ba58656810b67fd131eb9af3e3987863bf26c90": {
+    "title": "IBM Logo",
+    "platform": "chip8"
+  }
+}`
@@ -0,0 +1,241 @@
+// Package romdb identifies roms by the SHA-1 hash of their program data, and
+// looks up their title, author, platform and the settings they need, so front
+// ends can pick the quirks, clock speed and key mapping automatically.
+//
+// Databases are JSON objects that map the hash of a rom to its entry:
+//
+//   {
+//     "0123456789abcdef0123456789abcdef01234567": {
+//       "title": "Example",
+//       "author": "Someone",
+//       "platform": "schip",
+//       "quirks": "schip",
+//       "speed": 1200,
+//       "keys": {"left": 7, "right": 9, "a": 5}
+//     }
+//   }
+//
+// A small database is bundled with the emulator as a seed. Users extend or
+// override it with their own file, at UserPath, and with files read on top
+// of it. The identify command of chip8 prints the hash of a rom to add.
+package romdb
+
+import (
+    "chip8-emulator/chip8"
+    "crypto/sha1"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// ErrInvalidDatabase is returned when reading a database with invalid entries
+var ErrInvalidDatabase = errors.New("invalid rom database")
+
+// platformQuirks maps the platforms to the quirk profile used for entries
+// that do not name one
+var platformQuirks = map[string]string{
+    "chip8":  "vip",
+    "schip":  "schip",
+    "xochip": "xochip",
+}
+
+// Actions are the names of the key mapping of an entry, which front ends map
+// onto the arrow keys and two buttons
+var Actions = []string{"up", "down", "left", "right", "a", "b"}
+
+// Entry describes a rom
+type Entry struct {
+    Title    string         `json:"title"`
+    Author   string         `json:"author,omitempty"`
+    // Platform is the machine the rom was written for: chip8, schip or
+    // xochip
+    Platform string         `json:"platform,omitempty"`
+    // Quirks is the name of the quirk profile, by default the one of the
+    // platform
+    Quirks   string         `json:"quirks,omitempty"`
+    // Speed is the clock speed in instructions per second, 0 for the default
+    Speed    int            `json:"speed,omitempty"`
+    // Keys maps the Actions onto the keypad keys the rom uses for them
+    Keys     map[string]int `json:"keys,omitempty"`
+}
+
+// QuirksProfile returns the name of the quirk profile of the entry, or an
+// empty string if it has none
+func (e *Entry) QuirksProfile() string {
+    if e.Quirks != "" {
+        return e.Quirks
+    }
+    return platformQuirks[e.Platform]
+}
+
+// check returns an error if the entry names unknown settings
+func (e *Entry) check() error {
+    if _, ok := platformQuirks[e.Platform]; e.Platform != "" && !ok {
+        return fmt.Errorf("unknown platform: %q", e.Platform)
+    }
+    if _, ok := chip8.QuirksProfile(e.Quirks); e.Quirks != "" && !ok {
+        return fmt.Errorf("unknown quirk profile: %q", e.Quirks)
+    }
+    if e.Speed < 0 {
+        return fmt.Errorf("invalid speed: %d", e.Speed)
+    }
+    for action, key := range e.Keys {
+        if !isAction(action) {
+            return fmt.Errorf("unknown action: %q", action)
+        }
+        if key < 0 || key >= chip8.KeyCount {
+            return fmt.Errorf("invalid key for %s: %d", action, key)
+        }
+    }
+    return nil
+}
+
+func isAction(name string) bool {
+    for _, action := range Actions {
+        if action == name {
+            return true
+        }
+    }
+    return false
+}
+
+// Hash returns the hash that identifies a rom in a database
+func Hash(rom []byte) string {
+    sum := sha1.Sum(rom)
+    return hex.EncodeToString(sum[:])
+}
+
+// Database maps the hashes of roms to their entries
+type Database struct {
+    entries map[string]Entry
+}
+
+// New returns an empty database
+func New() *Database {
+    return &Database{entries: map[string]Entry{}}
+}
+
+// Bundled returns a database with the entries bundled with the emulator
+func Bundled() *Database {
+    db := New()
+    if err := db.Read(strings.NewReader(bundled)); err != nil {
+        panic(err)
+    }
+    return db
+}
+
+// Len returns the number of entries
+func (db *Database) Len() int {
+    return len(db.entries)
+}
+
+// Lookup returns the entry of a rom
+func (db *Database) Lookup(rom []byte) (Entry, bool) {
+    e, ok := db.entries[Hash(rom)]
+    return e, ok
+}
+
+// Settings are the quirk profile and clock speed a front end runs a rom with
+type Settings struct {
+    // Quirks is the name of the quirk profile
+    Quirks     string
+    // Speed is the clock speed in instructions per second
+    Speed      int
+    // KeepQuirks and KeepSpeed are set for the settings chosen by the user,
+    // which the entry of the rom does not replace
+    KeepQuirks bool
+    KeepSpeed  bool
+}
+
+// Select looks up a rom and replaces the settings with those of its entry,
+// except for the ones the user chose. Front ends call it before creating the
+// CPU, so the rom is loaded with the selected quirks.
+func (db *Database) Select(rom []byte, s *Settings) (Entry, bool) {
+    e, ok := db.Lookup(rom)
+    if !ok {
+        return e, false
+    }
+    if name := e.QuirksProfile(); name != "" && !s.KeepQuirks {
+        s.Quirks = name
+    }
+    if e.Speed > 0 && !s.KeepSpeed {
+        s.Speed = e.Speed
+    }
+    return e, true
+}
+
+// Add adds or replaces the entry of the rom with the given hash
+func (db *Database) Add(hash string, e Entry) error {
+    if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size * 2 {
+        return fmt.Errorf("%w: invalid hash: %q", ErrInvalidDatabase, hash)
+    }
+    if err := e.check(); err != nil {
+        return fmt.Errorf("%w: %s: %v", ErrInvalidDatabase, hash, err)
+    }
+    db.entries[strings.ToLower(hash)] = e
+    return nil
+}
+
+// Read adds the entries of a JSON database, replacing those of the same
+// roms. Nothing is added if any entry is invalid.
+func (db *Database) Read(r io.Reader) error {
+    var entries map[string]Entry
+    if err := json.NewDecoder(r).Decode(&entries); err != nil {
+        return fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+    }
+    checked := New()
+    for hash, e := range entries {
+        if err := checked.Add(hash, e); err != nil {
+            return err
+        }
+    }
+    for hash, e := range checked.entries {
+        db.entries[hash] = e
+    }
+    return nil
+}
+
+// ReadFile adds the entries of a JSON database file
+func (db *Database) ReadFile(path string) error {
+    f, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    if err := db.Read(f); err != nil {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    return nil
+}
+
+// UserPath returns the database file of the user, which is roms.json in the
+// chip8 directory of the user configuration directory
+func UserPath() (string, error) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, "chip8", "roms.json"), nil
+}
+
+// Open returns the bundled database, extended with the database of the user,
+// if it exists, and the given files
+func Open(paths ...string) (*Database, error) {
+    db := Bundled()
+    if user, err := UserPath(); err == nil {
+        if err := db.ReadFile(user); err != nil && !errors.Is(err, os.ErrNotExist) {
+            return nil, err
+        }
+    }
+    for _, path := range paths {
+        if err := db.ReadFile(path); err != nil {
+            return nil, err
+        }
+    }
+    return db, nil
+}
@@ -0,0 +1,178 @@
+package romdb
+
+import (
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+var rom = []byte{0x00, 0xE0, 0x12, 0x00}
+
+func Test_Hash(t *testing.T) {
+    if h := Hash(rom); h != "2cdd5bd3f4e30a4d56d9a8841ffcd5fbc2d0f735" {
+        t.Errorf("unexpected hash: %s", h)
+    }
+    if Hash(rom) == Hash(rom[:2]) {
+        t.Error("different roms should have different hashes")
+    }
+}
+
+func Test_Read_Lookup(t *testing.T) {
+    db := New()
+    err := db.Read(strings.NewReader(`{
+        "` + strings.ToUpper(Hash(rom)) + `": {
+            "title": "Test",
+            "author": "Someone",
+            "platform": "schip",
+            "speed": 1200,
+            "keys": {"left": 7, "right": 9}
+        }
+    }`))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    e, ok := db.Lookup(rom)
+    if !ok {
+        t.Fatal("rom not found")
+    }
+    if e.Title != "Test" || e.Author != "Someone" || e.Speed != 1200 || e.Keys["left"] != 7 || e.Keys["right"] != 9 {
+        t.Errorf("unexpected entry: %+v", e)
+    }
+    if e.QuirksProfile() != "schip" {
+        t.Errorf("expected the quirks of the platform, got %q", e.QuirksProfile())
+    }
+    if _, ok := db.Lookup(rom[:2]); ok {
+        t.Error("unknown rom should not be found")
+    }
+
+    // later databases override entries
+    if err := db.Read(strings.NewReader(`{"` + Hash(rom) + `": {"title": "Other", "platform": "chip8", "quirks": "modern"}}`)); err != nil {
+        t.Fatal(err)
+    }
+    if e, _ := db.Lookup(rom); e.Title != "Other" || e.QuirksProfile() != "modern" || db.Len() != 1 {
+        t.Errorf("unexpected entry after override: %+v", e)
+    }
+}
+
+func Test_Read_invalid(t *testing.T) {
+    hash := Hash(rom)
+    for _, data := range []string{
+        `[]`,
+        `{"1234": {"title": "short hash"}}`,
+        `{"` + hash + `": {"platform": "nes"}}`,
+        `{"` + hash + `": {"quirks": "unknown"}}`,
+        `{"` + hash + `": {"speed": -1}}`,
+        `{"` + hash + `": {"keys": {"jump": 1}}}`,
+        `{"` + hash + `": {"keys": {"up": 16}}}`,
+    } {
+        db := New()
+        if err := db.Read(strings.NewReader(data)); !errors.Is(err, ErrInvalidDatabase) {
+            t.Errorf("%s: expected ErrInvalidDatabase, got %v", data, err)
+        }
+        if db.Len() != 0 {
+            t.Errorf("%s: no entries should be added", data)
+        }
+    }
+}
+
+func Test_Select(t *testing.T) {
+    db := New()
+    if err := db.Add(Hash(rom), Entry{Title: "Test", Platform: "schip", Speed: 1200}); err != nil {
+        t.Fatal(err)
+    }
+
+    s := Settings{Quirks: "modern", Speed: 600}
+    if e, ok := db.Select(rom, &s); !ok || e.Title != "Test" {
+        t.Errorf("unexpected entry: %+v", e)
+    }
+    if s.Quirks != "schip" || s.Speed != 1200 {
+        t.Errorf("expected the settings of the entry, got %+v", s)
+    }
+
+    s = Settings{Quirks: "modern", Speed: 600, KeepQuirks: true, KeepSpeed: true}
+    db.Select(rom, &s)
+    if s.Quirks != "modern" || s.Speed != 600 {
+        t.Errorf("expected the settings of the user, got %+v", s)
+    }
+
+    s = Settings{Quirks: "modern", Speed: 600}
+    if _, ok := db.Select(rom[:2], &s); ok || s.Quirks != "modern" || s.Speed != 600 {
+        t.Errorf("unknown rom should not change the settings: %+v", s)
+    }
+}
+
+func Test_Open(t *testing.T) {
+    dir, err := ioutil.TempDir("", "romdb")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    // the configuration directory on Linux, macOS and Windows
+    for _, name := range []string{"XDG_CONFIG_HOME", "HOME", "AppData"} {
+        defer os.Setenv(name, os.Getenv(name))
+        os.Setenv(name, dir)
+    }
+    user, err := UserPath()
+    if err != nil {
+        t.Fatal(err)
+    }
+    os.MkdirAll(filepath.Dir(user), 0755)
+    other := []byte{0x00, 0xE0}
+    if err := ioutil.WriteFile(user, []byte(`{"` + Hash(other) + `": {"title": "User"}}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    path := filepath.Join(dir, "extra.json")
+    if err := ioutil.WriteFile(path, []byte(`{"` + Hash(rom) + `": {"title": "Extra"}}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    db, err := Open(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if e, ok := db.Lookup(rom); !ok || e.Title != "Extra" {
+        t.Errorf("expected the entry of the extra database, got %+v", e)
+    }
+    if e, ok := db.Lookup(other); !ok || e.Title != "User" {
+        t.Errorf("expected the entry of the user database, got %+v", e)
+    }
+    if db.Len() != Bundled().Len() + 2 {
+        t.Errorf("expected the bundled entries and two more, got %d", db.Len())
+    }
+
+    if _, err := Open(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("expected a missing file to fail, got %v", err)
+    }
+}
+
+func Test_Bundled(t *testing.T) {
+    db := Bundled()
+    if db.Len() == 0 {
+        t.Fatal("the bundled database is empty")
+    }
+
+    // every rom in testdata is a released file with a bundled entry
+    paths, err := filepath.Glob(filepath.Join("testdata", "*.ch8"))
+    if err != nil || len(paths) == 0 {
+        t.Fatalf("no roms in testdata: %v", err)
+    }
+    for _, path := range paths {
+        data, err := ioutil.ReadFile(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if _, ok := db.Lookup(data); !ok {
+            t.Errorf("%s: no bundled entry for %s", path, Hash(data))
+        }
+    }
+
+    data, _ := ioutil.ReadFile(filepath.Join("testdata", "ibm.ch8"))
+    if e, ok := db.Lookup(data); !ok || e.Title != "IBM Logo" || e.QuirksProfile() != "vip" {
+        t.Errorf("unexpected entry: %+v", e)
+    }
+}
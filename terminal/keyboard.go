@@ -0,0 +1,115 @@
+package terminal
+
+import (
+    "chip8-emulator/chip8"
+    "io"
+    "sync"
+)
+
+// keyMap maps the hexadecimal keypad onto the left side of a keyboard, in
+// the same layout as the window of the chip8 command
+const keyMap = "x123qweasdzc4rfv"
+
+const (
+    ctrlC  = 0x03
+    escape = 0x1b
+)
+
+// DefaultHold is the number of frames a key stays down after it was typed.
+// Terminals only report key presses, and repeat them while a key is held,
+// so a key is released once it has not been repeated for a while.
+const DefaultHold = 10
+
+// Keyboard reads the keypad from a terminal in raw mode, and implements
+// chip8.InputSource. Escape sequences, such as those of the arrow keys, are
+// ignored, and Ctrl-C quits.
+type Keyboard struct {
+    // Hold is the number of frames a key stays down after it was typed
+    Hold int
+    mu   sync.Mutex
+    held [chip8.KeyCount]int
+    quit chan struct{}
+}
+
+// NewKeyboard returns a keyboard that reads from r until it fails or Ctrl-C
+// is typed
+func NewKeyboard(r io.Reader) *Keyboard {
+    k := &Keyboard{
+        Hold: DefaultHold,
+        quit: make(chan struct{}),
+    }
+    go k.read(r)
+    return k
+}
+
+// KeyState returns the keys that were typed in the last Hold frames
+func (k *Keyboard) KeyState() [chip8.KeyCount]bool {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    var keys [chip8.KeyCount]bool
+    for key, frames := range k.held {
+        keys[key] = frames > 0
+    }
+    return keys
+}
+
+// Frame counts down the frames the keys are held for. It is called once at
+// the start of every frame.
+func (k *Keyboard) Frame() {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    for key := range k.held {
+        if k.held[key] > 0 {
+            k.held[key]--
+        }
+    }
+}
+
+// Quit returns a channel that is closed when Ctrl-C was typed or the input
+// ended
+func (k *Keyboard) Quit() <-chan struct{} {
+    return k.quit
+}
+
+func (k *Keyboard) read(r io.Reader) {
+    defer close(k.quit)
+    buf := make([]byte, 64)
+    for {
+        n, err := r.Read(buf)
+        // sequence is set while skipping the rest of an escape sequence.
+        // Sequences arrive in a single read, so a lone Escape does not
+        // swallow the keys typed after it.
+        sequence := false
+        for _, b := range buf[:n] {
+            switch {
+            case b == ctrlC:
+                return
+            case sequence:
+                // sequences end with a letter, after a [ or O
+                sequence = b == escape || b == '[' || b == 'O' || b < '@' || b > '~'
+            case b == escape:
+                sequence = true
+            default:
+                k.press(b)
+            }
+        }
+        if err != nil {
+            return
+        }
+    }
+}
+
+// press holds the key typed as b, if it is on the keypad
+func (k *Keyboard) press(b byte) {
+    if b >= 'A' && b <= 'Z' {
+        b += 'a' - 'A'
+    }
+    for key := range keyMap {
+        if keyMap[key] == b {
+            k.mu.Lock()
+            k.held[key] = k.Hold
+            k.mu.Unlock()
+            return
+        }
+    }
+}
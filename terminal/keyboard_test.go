@@ -0,0 +1,54 @@
+package terminal
+
+import (
+    "io"
+    "strings"
+    "testing"
+    "time"
+)
+
+// awaitQuit waits until the keyboard has read all input
+func awaitQuit(t *testing.T, k *Keyboard) {
+    select {
+    case <-k.Quit():
+    case <-time.After(time.Second):
+        t.Fatal("keyboard did not quit")
+    }
+}
+
+func Test_Keyboard(t *testing.T) {
+    k := NewKeyboard(strings.NewReader("1V\x1b[Ax"))
+    awaitQuit(t, k)
+
+    keys := k.KeyState()
+    for key, pressed := range keys {
+        expected := key == 0x1 || key == 0xF || key == 0x0
+        if pressed != expected {
+            t.Errorf("key %X: expected %v, got %v", key, expected, pressed)
+        }
+    }
+
+    for i := 0; i < DefaultHold - 1; i++ {
+        k.Frame()
+    }
+    if !k.KeyState()[0x1] {
+        t.Error("key should be held for the hold frames")
+    }
+    k.Frame()
+    if k.KeyState()[0x1] {
+        t.Error("key should be released after the hold frames")
+    }
+}
+
+func Test_Keyboard_quit(t *testing.T) {
+    r, w := io.Pipe()
+    defer w.Close()
+    k := NewKeyboard(r)
+
+    w.Write([]byte("q\x03w"))
+    awaitQuit(t, k)
+
+    if keys := k.KeyState(); !keys[0x4] || keys[0x5] {
+        t.Errorf("unexpected keys after Ctrl-C: %v", keys)
+    }
+}
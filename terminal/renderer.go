@@ -0,0 +1,89 @@
+// Package terminal shows the emulation in a terminal, so it can be used over
+// SSH and where OpenGL is not available.
+//
+// The Renderer draws two rows of pixels per line of text, using the upper
+// half block character with 24-bit ANSI colors for the top and bottom pixel.
+// The Keyboard reads the keypad from the terminal input, which has to be in
+// raw mode.
+package terminal
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "fmt"
+    "image/color"
+    "io"
+)
+
+const (
+    halfBlock   = "▀"
+    clearScreen = "\x1b[2J"
+    home        = "\x1b[H"
+    hideCursor  = "\x1b[?25l"
+    showCursor  = "\x1b[?25h"
+    resetColors = "\x1b[0m"
+)
+
+// Renderer draws frames to a terminal, and implements chip8.Display
+type Renderer struct {
+    W       io.Writer
+    Palette chip8.Palette
+    // last is the frame shown last, frames that are equal to it are not
+    // drawn again
+    last    *chip8.Frame
+}
+
+// NewRenderer returns a renderer that writes to w
+func NewRenderer(w io.Writer, palette chip8.Palette) *Renderer {
+    return &Renderer{W: w, Palette: palette}
+}
+
+// Show draws the frame over the previous one, starting at the top left of
+// the terminal. The screen is cleared when the resolution changes.
+func (r *Renderer) Show(f *chip8.Frame) error {
+    if f.Equal(r.last) {
+        return nil
+    }
+    var b bytes.Buffer
+    if r.last == nil || r.last.Width != f.Width || r.last.Height != f.Height {
+        b.WriteString(hideCursor + resetColors + clearScreen)
+    }
+    b.WriteString(home)
+    for y := 0; y < f.Height; y += 2 {
+        var fg, bg string
+        for x := 0; x < f.Width; x++ {
+            top := r.color(f.At(x, y))
+            bottom := r.color(0)
+            if y + 1 < f.Height {
+                bottom = r.color(f.At(x, y + 1))
+            }
+            if top != fg {
+                fmt.Fprintf(&b, "\x1b[38;2;%sm", top)
+                fg = top
+            }
+            if bottom != bg {
+                fmt.Fprintf(&b, "\x1b[48;2;%sm", bottom)
+                bg = bottom
+            }
+            b.WriteString(halfBlock)
+        }
+        // the terminal is in raw mode, which does not return the carriage
+        b.WriteString(resetColors + "\r\n")
+    }
+    r.last = f
+    _, err := r.W.Write(b.Bytes())
+    return err
+}
+
+// Close restores the colors and cursor of the terminal
+func (r *Renderer) Close() error {
+    _, err := io.WriteString(r.W, resetColors + showCursor)
+    return err
+}
+
+// color returns the palette color of a pixel as the r;g;b parameters of an
+// ANSI escape sequence
+func (r *Renderer) color(pixel byte) string {
+    c := color.RGBAModel.Convert(r.Palette[int(pixel) % len(r.Palette)]).(color.RGBA)
+    return fmt.Sprintf("%d;%d;%d", c.R, c.G, c.B)
+}
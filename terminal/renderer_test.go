@@ -0,0 +1,77 @@
+package terminal
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "strings"
+    "testing"
+)
+
+func Test_Renderer(t *testing.T) {
+    var out bytes.Buffer
+    r := NewRenderer(&out, chip8.DefaultPalette)
+    f := &chip8.Frame{Width: 2, Height: 2, Pixels: []byte{1, 0, 0, 2}}
+
+    if err := r.Show(f); err != nil {
+        t.Fatal(err)
+    }
+    expected := hideCursor + resetColors + clearScreen + home +
+        "\x1b[38;2;255;255;255m\x1b[48;2;0;0;0m" + halfBlock +
+        "\x1b[38;2;0;0;0m\x1b[48;2;170;170;170m" + halfBlock +
+        resetColors + "\r\n"
+    if out.String() != expected {
+        t.Errorf("unexpected output:\n%q\nexpected:\n%q", out.String(), expected)
+    }
+
+    out.Reset()
+    if err := r.Show(&chip8.Frame{Width: 2, Height: 2, Pixels: []byte{1, 0, 0, 2}}); err != nil {
+        t.Fatal(err)
+    }
+    if out.Len() != 0 {
+        t.Errorf("unchanged frame should not be drawn: %q", out.String())
+    }
+
+    out.Reset()
+    if err := r.Show(&chip8.Frame{Width: 2, Height: 2, Pixels: []byte{0, 0, 0, 0}}); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.HasPrefix(out.String(), home) {
+        t.Errorf("frame of the same size should be drawn over the last: %q", out.String())
+    }
+    if strings.Count(out.String(), "\x1b[38;2;") != 1 {
+        t.Errorf("colors should only be set when they change: %q", out.String())
+    }
+
+    out.Reset()
+    if err := r.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if out.String() != resetColors + showCursor {
+        t.Errorf("unexpected output on close: %q", out.String())
+    }
+}
+
+func Test_Renderer_resolution(t *testing.T) {
+    var out bytes.Buffer
+    r := NewRenderer(&out, chip8.DefaultPalette)
+    c, err := chip8.NewCPU(nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    r.Show(c.Frame())
+    c.HighRes = true
+    out.Reset()
+    if err := r.Show(c.Frame()); err != nil {
+        t.Fatal(err)
+    }
+
+    if !strings.Contains(out.String(), clearScreen) {
+        t.Error("screen should be cleared when the resolution changes")
+    }
+    if lines := strings.Count(out.String(), "\r\n"); lines != chip8.HiResHeight / 2 {
+        t.Errorf("unexpected number of lines: %d", lines)
+    }
+    if blocks := strings.Count(out.String(), halfBlock); blocks != chip8.HiResWidth * chip8.HiResHeight / 2 {
+        t.Errorf("unexpected number of blocks: %d", blocks)
+    }
+}
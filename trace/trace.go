@@ -0,0 +1,185 @@
+// Package trace records the instructions executed by a CPU as JSON Lines or
+// compact text, so traces can be compared between runs or against other
+// emulators.
+package trace
+
+import (
+    "chip8-emulator/chip8"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// Format selects how the instructions are written
+type Format int
+
+const (
+    // JSON writes one JSON object per line for every instruction
+    JSON Format = iota
+    // Text writes one line of text for every instruction
+    Text
+)
+
+// ParseFormat returns the format called "json" or "text"
+func ParseFormat(name string) (Format, error) {
+    switch strings.ToLower(name) {
+    case "json", "jsonl":
+        return JSON, nil
+    case "text":
+        return Text, nil
+    }
+    return 0, fmt.Errorf("unknown trace format: %q", name)
+}
+
+// Range is an inclusive range of addresses
+type Range struct {
+    From uint16
+    To   uint16
+}
+
+// ParseRange parses a range of hexadecimal addresses, such as "200-2FF", or
+// a single address
+func ParseRange(s string) (Range, error) {
+    parts := strings.SplitN(s, "-", 2)
+    from, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 16, 16)
+    if err != nil {
+        return Range{}, fmt.Errorf("invalid address range: %q", s)
+    }
+    to := from
+    if len(parts) == 2 {
+        if to, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 16, 16); err != nil || to < from {
+            return Range{}, fmt.Errorf("invalid address range: %q", s)
+        }
+    }
+    return Range{From: uint16(from), To: uint16(to)}, nil
+}
+
+func (r Range) Contains(addr uint16) bool {
+    return addr >= r.From && addr <= r.To
+}
+
+// Change is a register, I, the stack pointer or a stack entry changed by an
+// instruction
+type Change struct {
+    Name  string
+    Value int
+}
+
+// Changes returns what changed between before and after, in the order V0 to
+// VF, I, SP and the stack entries
+func Changes(before, after *chip8.TraceState) []Change {
+    changes := []Change{}
+    for x := range after.Register {
+        if before.Register[x] != after.Register[x] {
+            changes = append(changes, Change{fmt.Sprintf("v%x", x), int(after.Register[x])})
+        }
+    }
+    if before.Index != after.Index {
+        changes = append(changes, Change{"i", int(after.Index)})
+    }
+    if before.StackPointer != after.StackPointer {
+        changes = append(changes, Change{"sp", int(after.StackPointer)})
+    }
+    for n := range after.Stack {
+        if before.Stack[n] != after.Stack[n] {
+            changes = append(changes, Change{fmt.Sprintf("stack[%d]", n), int(after.Stack[n])})
+        }
+    }
+    return changes
+}
+
+// Record is a single traced instruction, as written in the JSON format
+type Record struct {
+    Cycle    uint64         `json:"cycle"`
+    PC       uint16         `json:"pc"`
+    OpCode   uint16         `json:"opcode"`
+    Mnemonic string         `json:"mnemonic"`
+    Changes  map[string]int `json:"changes,omitempty"`
+    Error    string         `json:"error,omitempty"`
+}
+
+// Writer is a chip8.Tracer that writes every instruction in the address
+// ranges to W
+type Writer struct {
+    W      io.Writer
+    Format Format
+    // Ranges limits the trace to the instructions in these ranges. All
+    // instructions are written if it is empty.
+    Ranges []Range
+    // Cycles counts the instructions executed, including those that were
+    // not written
+    Cycles uint64
+    // Err is the first error writing the trace, after which nothing more is
+    // written
+    Err error
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+    return &Writer{
+        W:      w,
+        Format: format,
+    }
+}
+
+// Trace implements chip8.Tracer
+func (t *Writer) Trace(before, after *chip8.TraceState, err error) {
+    t.Cycles++
+    if t.Err != nil || !t.traced(before.ProgramCounter) {
+        return
+    }
+    mnemonic, _ := chip8.Mnemonic(before.OpCode, nil)
+    changes := Changes(before, after)
+    if t.Format == Text {
+        t.Err = t.writeText(before, mnemonic, changes, err)
+        return
+    }
+
+    r := &Record{
+        Cycle:    t.Cycles,
+        PC:       before.ProgramCounter,
+        OpCode:   before.OpCode,
+        Mnemonic: mnemonic,
+    }
+    if len(changes) > 0 {
+        r.Changes = map[string]int{}
+        for _, c := range changes {
+            r.Changes[c.Name] = c.Value
+        }
+    }
+    if err != nil {
+        r.Error = err.Error()
+    }
+    line, _ := json.Marshal(r)
+    _, t.Err = t.W.Write(append(line, '\n'))
+}
+
+// writeText writes the cycle, address, opcode and mnemonic followed by the
+// changes, such as "12 20A 7101 add v1, 0x01 v1=06"
+func (t *Writer) writeText(before *chip8.TraceState, mnemonic string, changes []Change, err error) error {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%d %03X %04X %s", t.Cycles, before.ProgramCounter, before.OpCode, mnemonic)
+    for _, c := range changes {
+        fmt.Fprintf(&b, " %s=%02X", c.Name, c.Value)
+    }
+    if err != nil {
+        fmt.Fprintf(&b, " error=%q", err.Error())
+    }
+    b.WriteByte('\n')
+    _, werr := io.WriteString(t.W, b.String())
+    return werr
+}
+
+// traced returns true if the instruction at addr is in one of the ranges
+func (t *Writer) traced(addr uint16) bool {
+    if len(t.Ranges) == 0 {
+        return true
+    }
+    for _, r := range t.Ranges {
+        if r.Contains(addr) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,131 @@
+package trace
+
+import (
+    "bytes"
+    "chip8-emulator/chip8"
+    "encoding/json"
+    "errors"
+    "strings"
+    "testing"
+)
+
+func newCPU(t *testing.T, tracer *Writer, ops ...uint16) *chip8.CPU {
+    t.Helper()
+    c, err := chip8.NewCPU(chip8.Build(ops...))
+    if err != nil {
+        t.Fatal(err)
+    }
+    c.Tracer = tracer
+    return c
+}
+
+func Test_JSON(t *testing.T) {
+    var b bytes.Buffer
+    tracer := NewWriter(&b, JSON)
+    c := newCPU(t, tracer,
+        chip8.LD(0x1, 0x12),
+        chip8.CALL(0x208),
+        chip8.NOP(),
+        chip8.NOP(),
+        chip8.LDI(0x300),
+    )
+    for i := 0; i < 3; i++ {
+        c.Cycle()
+    }
+
+    lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+    if len(lines) != 3 {
+        t.Fatalf("unexpected number of lines: %d", len(lines))
+    }
+    records := make([]Record, len(lines))
+    for i, line := range lines {
+        if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    first := records[0]
+    if first.Cycle != 1 || first.PC != 0x200 || first.OpCode != 0x6112 || first.Mnemonic != "ld v1, 0x12" {
+        t.Errorf("unexpected record: %+v", first)
+    }
+    if len(first.Changes) != 1 || first.Changes["v1"] != 0x12 {
+        t.Errorf("unexpected changes: %v", first.Changes)
+    }
+    if call := records[1].Changes; len(call) != 2 || call["sp"] != 1 || call["stack[0]"] != 0x202 {
+        t.Errorf("unexpected changes: %v", call)
+    }
+    if last := records[2]; last.PC != 0x208 || last.Changes["i"] != 0x300 {
+        t.Errorf("unexpected record: %+v", last)
+    }
+}
+
+func Test_Text(t *testing.T) {
+    var b bytes.Buffer
+    c := newCPU(t, NewWriter(&b, Text),
+        chip8.LD(0xA, 0x5),
+        chip8.RET(),
+    )
+    c.Cycle()
+    c.Cycle()
+
+    expected := "1 200 6A05 ld vA, 0x05 va=05\n" +
+        "2 202 00EE ret error=\"stack underflow at 202 (opcode 00EE)\"\n"
+    if b.String() != expected {
+        t.Errorf("unexpected trace:\n%s", b.String())
+    }
+}
+
+func Test_Ranges(t *testing.T) {
+    var b bytes.Buffer
+    tracer := NewWriter(&b, Text)
+    r, err := ParseRange("202-203")
+    if err != nil {
+        t.Fatal(err)
+    }
+    tracer.Ranges = []Range{r}
+    c := newCPU(t, tracer,
+        chip8.LD(0x1, 0x1),
+        chip8.LD(0x2, 0x2),
+        chip8.LD(0x3, 0x3),
+    )
+    for i := 0; i < 3; i++ {
+        c.Cycle()
+    }
+
+    if b.String() != "2 202 6202 ld v2, 0x02 v2=02\n" {
+        t.Errorf("unexpected trace:\n%s", b.String())
+    }
+    if tracer.Cycles != 3 {
+        t.Errorf("unexpected cycle count: %d", tracer.Cycles)
+    }
+}
+
+func Test_ParseRange(t *testing.T) {
+    if r, err := ParseRange("2A0"); err != nil || r != (Range{0x2A0, 0x2A0}) {
+        t.Errorf("unexpected range: %v %v", r, err)
+    }
+    for _, s := range []string{"", "xyz", "300-200", "200-"} {
+        if _, err := ParseRange(s); err == nil {
+            t.Errorf("expected an error for %q", s)
+        }
+    }
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+    return 0, errors.New("disk full")
+}
+
+func Test_write_error(t *testing.T) {
+    tracer := NewWriter(failingWriter{}, JSON)
+    c := newCPU(t, tracer, chip8.NOP(), chip8.NOP())
+
+    c.Cycle()
+    if err := c.Cycle(); err != nil {
+        t.Errorf("trace errors should not stop the cpu: %v", err)
+    }
+    if tracer.Err == nil || tracer.Cycles != 2 {
+        t.Errorf("unexpected state: %v, %d cycles", tracer.Err, tracer.Cycles)
+    }
+}
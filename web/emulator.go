@@ -0,0 +1,124 @@
+// Package web runs the emulator in a browser. The Emulator advances a CPU
+// from the timestamps of requestAnimationFrame and renders the display to
+// RGBA pixels for a canvas, and the Keyboard maps keyboard events onto the
+// keypad.
+//
+// The package is independent of syscall/js, except for the helpers to copy
+// bytes from and to JavaScript, so it can be tested without a browser. The
+// tests also run on WebAssembly in Node, with the runner of the Go release:
+//
+//   GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./web
+//
+// The front end itself is in cmd/chip8-wasm.
+package web
+
+import (
+    "chip8-emulator/chip8"
+    "errors"
+    "image/color"
+)
+
+const (
+    // frameTime is the duration of a 60 Hz frame in milliseconds, the unit
+    // of the requestAnimationFrame timestamps
+    frameTime = 1000.0 / chip8.TimerFrequency
+    // maxLag is the most time caught up with at once, so the emulation does
+    // not race after the page was in the background
+    maxLag = 250.0
+    // jitter is how early a frame may run. The timestamps of a 60 Hz display
+    // are not exactly a frame apart, and would otherwise run no frame and
+    // then two every now and then.
+    jitter = 1.0
+)
+
+// Emulator runs a CPU in the frames of a browser, which come at the refresh
+// rate of the display rather than at 60 Hz
+type Emulator struct {
+    CPU       *chip8.CPU
+    Scheduler *chip8.Scheduler
+    Keyboard  *Keyboard
+    Palette   chip8.Palette
+    // Err is the error that stopped the emulation, if any. Programs that
+    // exit with 00FD stop with chip8.ErrExit.
+    Err       error
+
+    // last is the timestamp of the previous animation frame, and lag the
+    // time since then that has not been emulated yet
+    last      float64
+    lag       float64
+    started   bool
+    pixels    []byte
+}
+
+// NewEmulator returns an emulator that runs the program with the quirks, at
+// the clock speed in instructions per second
+func NewEmulator(program []byte, quirks chip8.Quirks, clockSpeed int) (*Emulator, error) {
+    c, err := chip8.NewCPUWithQuirks(program, quirks)
+    if err != nil {
+        return nil, err
+    }
+    keyboard := &Keyboard{}
+    c.Input = keyboard
+    return &Emulator{
+        CPU:       c,
+        Scheduler: chip8.NewScheduler(c, clockSpeed),
+        Keyboard:  keyboard,
+        Palette:   chip8.DefaultPalette,
+    }, nil
+}
+
+// Animate runs the frames that fit in the time up to now, the timestamp of
+// an animation frame in milliseconds, and returns how many were run. The
+// first call only starts the clock.
+func (e *Emulator) Animate(now float64) int {
+    if !e.started || now < e.last {
+        e.started = true
+        e.last = now
+        return 0
+    }
+    e.lag += now - e.last
+    e.last = now
+    if e.lag > maxLag {
+        e.lag = maxLag
+    }
+    frames := 0
+    for e.lag >= frameTime - jitter && e.Err == nil {
+        e.lag -= frameTime
+        e.Err = e.Scheduler.Frame()
+        frames++
+    }
+    return frames
+}
+
+// Exited returns true if the program ended with 00FD
+func (e *Emulator) Exited() bool {
+    return errors.Is(e.Err, chip8.ErrExit)
+}
+
+// Sound returns true while the sound timer is running
+func (e *Emulator) Sound() bool {
+    return e.Err == nil && e.CPU.SoundTimer > 0
+}
+
+// Render returns the display as RGBA pixels, 4 bytes per pixel row by row,
+// as used by the ImageData of a canvas. The returned slice is reused by the
+// next call.
+func (e *Emulator) Render() (pixels []byte, width, height int) {
+    f := e.CPU.Frame()
+    if cap(e.pixels) < len(f.Pixels) * 4 {
+        e.pixels = make([]byte, len(f.Pixels) * 4)
+    }
+    e.pixels = e.pixels[:len(f.Pixels) * 4]
+    var palette [1 << chip8.PlaneCount]color.RGBA
+    for i, c := range e.Palette {
+        palette[i] = color.RGBAModel.Convert(c).(color.RGBA)
+    }
+    for i, p := range f.Pixels {
+        c := palette[int(p) % len(palette)]
+        e.pixels[i * 4] = c.R
+        e.pixels[i * 4 + 1] = c.G
+        e.pixels[i * 4 + 2] = c.B
+        e.pixels[i * 4 + 3] = c.A
+    }
+    return e.pixels, f.Width, f.Height
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+    "chip8-emulator/chip8"
+    "testing"
+)
+
+func newEmulator(t *testing.T, ops ...uint16) *Emulator {
+    e, err := NewEmulator(chip8.Build(ops...), chip8.QuirksModern, chip8.DefaultClockSpeed)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return e
+}
+
+func Test_Animate(t *testing.T) {
+    e := newEmulator(t, chip8.JP(0x200))
+
+    if frames := e.Animate(1000); frames != 0 {
+        t.Errorf("the first animation frame should only start the clock, ran %d frames", frames)
+    }
+    // a 120 Hz display runs a frame every other animation frame
+    ran := 0
+    for i := 1; i <= 12; i++ {
+        ran += e.Animate(1000 + float64(i) * 1000 / 120)
+    }
+    if ran != 6 {
+        t.Errorf("expected 6 frames in 100ms, got %d", ran)
+    }
+
+    // after the page was in the background it does not catch up all at once
+    if frames := e.Animate(60000); frames != 15 {
+        t.Errorf("expected the lag to be capped at 15 frames, got %d", frames)
+    }
+}
+
+func Test_Animate_error(t *testing.T) {
+    e := newEmulator(t, chip8.LD(0, 0x05), chip8.LD_ST_VX(0), chip8.EXIT())
+    e.CPU.Quirks = chip8.QuirksSuperChip
+    e.Animate(0)
+
+    if frames := e.Animate(100); frames != 1 {
+        t.Errorf("expected the emulation to stop after 1 frame, ran %d", frames)
+    }
+    if !e.Exited() {
+        t.Errorf("expected the program to exit, got %v", e.Err)
+    }
+    if e.Sound() {
+        t.Error("no sound should play after the program exited")
+    }
+    if frames := e.Animate(200); frames != 0 {
+        t.Errorf("no frames should run after the program exited, ran %d", frames)
+    }
+}
+
+func Test_Render(t *testing.T) {
+    e := newEmulator(t)
+    e.CPU.DisplayBuffer[1][0] = 1
+
+    pixels, width, height := e.Render()
+
+    if width != 64 || height != 32 || len(pixels) != 64 * 32 * 4 {
+        t.Fatalf("unexpected size: %dx%d with %d bytes", width, height, len(pixels))
+    }
+    if pixels[0] != 0 || pixels[3] != 255 {
+        t.Errorf("unexpected unlit pixel: %v", pixels[:4])
+    }
+    if pixels[4] != 255 || pixels[5] != 255 || pixels[6] != 255 || pixels[7] != 255 {
+        t.Errorf("unexpected lit pixel: %v", pixels[4:8])
+    }
+
+    e.CPU.HighRes = true
+    if _, width, height := e.Render(); width != 128 || height != 64 {
+        t.Errorf("unexpected high resolution size: %dx%d", width, height)
+    }
+}
@@ -0,0 +1,25 @@
+//go:build js && wasm
+// +build js,wasm
+
+package web
+
+import (
+    "syscall/js"
+)
+
+// Bytes copies the contents of an ArrayBuffer or Uint8Array, such as a file
+// read in the browser
+func Bytes(v js.Value) []byte {
+    if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+        v = js.Global().Get("Uint8Array").New(v)
+    }
+    b := make([]byte, v.Get("length").Int())
+    js.CopyBytesToGo(b, v)
+    return b
+}
+
+// CopyPixels copies the RGBA pixels returned by Render into the data of an
+// ImageData of the same size
+func CopyPixels(imageData js.Value, pixels []byte) {
+    js.CopyBytesToJS(imageData.Get("data"), pixels)
+}
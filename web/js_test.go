@@ -0,0 +1,37 @@
+//go:build js && wasm
+// +build js,wasm
+
+package web
+
+import (
+    "syscall/js"
+    "testing"
+)
+
+func Test_Bytes(t *testing.T) {
+    array := js.Global().Get("Uint8Array").New(3)
+    array.SetIndex(0, 0x12)
+    array.SetIndex(2, 0xFF)
+
+    for _, v := range []js.Value{array, array.Get("buffer")} {
+        if b := Bytes(v); len(b) != 3 || b[0] != 0x12 || b[1] != 0 || b[2] != 0xFF {
+            t.Errorf("unexpected bytes: %v", b)
+        }
+    }
+}
+
+func Test_CopyPixels(t *testing.T) {
+    // ImageData is not available outside of browsers, but only its data is
+    // used
+    imageData := js.Global().Get("Object").New()
+    imageData.Set("data", js.Global().Get("Uint8ClampedArray").New(4))
+
+    CopyPixels(imageData, []byte{1, 2, 3, 255})
+
+    data := imageData.Get("data")
+    for i, expected := range []int{1, 2, 3, 255} {
+        if v := data.Index(i).Int(); v != expected {
+            t.Errorf("byte %d: expected %d, got %d", i, expected, v)
+        }
+    }
+}
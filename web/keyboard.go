@@ -0,0 +1,75 @@
+package web
+
+import (
+    "chip8-emulator/chip8"
+    "sync"
+)
+
+// KeyCodes maps the hexadecimal keypad onto the KeyboardEvent.code values of
+// the left side of a keyboard, in the same layout as the chip8 command. Codes
+// name the physical keys, so the layout does not change with the language of
+// the keyboard.
+var KeyCodes = [chip8.KeyCount]string{
+    "KeyX",   // 0
+    "Digit1", // 1
+    "Digit2", // 2
+    "Digit3", // 3
+    "KeyQ",   // 4
+    "KeyW",   // 5
+    "KeyE",   // 6
+    "KeyA",   // 7
+    "KeyS",   // 8
+    "KeyD",   // 9
+    "KeyZ",   // A
+    "KeyC",   // B
+    "Digit4", // C
+    "KeyR",   // D
+    "KeyF",   // E
+    "KeyV",   // F
+}
+
+// Keyboard holds the keypad state from browser keyboard events, and
+// implements chip8.InputSource
+type Keyboard struct {
+    mu   sync.Mutex
+    keys [chip8.KeyCount]bool
+}
+
+// KeyState returns the keys that are held down
+func (k *Keyboard) KeyState() [chip8.KeyCount]bool {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    return k.keys
+}
+
+// Press handles a keydown event with the given code. It returns false if the
+// key is not on the keypad, so the browser can handle it instead.
+func (k *Keyboard) Press(code string) bool {
+    return k.set(code, true)
+}
+
+// Release handles a keyup event with the given code. It returns false if the
+// key is not on the keypad.
+func (k *Keyboard) Release(code string) bool {
+    return k.set(code, false)
+}
+
+// Reset releases all keys, for when the page loses focus and the keyup
+// events go elsewhere
+func (k *Keyboard) Reset() {
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    k.keys = [chip8.KeyCount]bool{}
+}
+
+func (k *Keyboard) set(code string, pressed bool) bool {
+    for key, c := range KeyCodes {
+        if c == code {
+            k.mu.Lock()
+            k.keys[key] = pressed
+            k.mu.Unlock()
+            return true
+        }
+    }
+    return false
+}
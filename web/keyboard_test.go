@@ -0,0 +1,29 @@
+package web
+
+import (
+    "testing"
+)
+
+func Test_Keyboard(t *testing.T) {
+    k := &Keyboard{}
+
+    if !k.Press("KeyV") || !k.Press("Digit1") {
+        t.Error("keypad keys should be handled")
+    }
+    if k.Press("Space") || k.Release("Digit5") {
+        t.Error("other keys should not be handled")
+    }
+    if keys := k.KeyState(); !keys[0xF] || !keys[0x1] || keys[0x0] {
+        t.Errorf("unexpected keys: %v", keys)
+    }
+
+    k.Release("KeyV")
+    if keys := k.KeyState(); keys[0xF] || !keys[0x1] {
+        t.Errorf("unexpected keys after release: %v", keys)
+    }
+
+    k.Reset()
+    if keys := k.KeyState(); keys[0x1] {
+        t.Errorf("unexpected keys after reset: %v", keys)
+    }
+}